@@ -2,89 +2,528 @@
 package main
 
 import (
-	"crypto/rand"
+	"bufio"
+	"bytes"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/catalogfi/tools/pkg/cryptutil"
 )
 
 func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line given by args and returns the exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
 	// Define command line flags
-	decryptMode := flag.Bool("decrypt", false, "Decrypt mode")
-	generate := flag.Bool("generate-key", false, "Generate a new random AES-256 key")
-	key := flag.String("key", "", "Hex-encoded AES-256 key (64 characters)")
-	input := flag.String("input", "", "Input string to encrypt/decrypt")
-	flag.Parse()
+	fs := flag.NewFlagSet("cryptutil", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	decryptMode := fs.Bool("decrypt", false, "Decrypt mode")
+	generate := fs.Bool("generate-key", false, "Generate a new random AES-256 key")
+	key := fs.String("key", "", "Hex-encoded AES-256 key (64 characters)")
+	keyFile := fs.String("key-file", "", "File holding the hex-encoded key, to keep it out of the shell history")
+	keyEnv := fs.String("key-env", "", "Name of the environment variable holding the hex-encoded key")
+	passphrase := fs.Bool("passphrase", false, "Prompt for a passphrase to derive the key from instead of using a hex key")
+	passphraseFile := fs.String("passphrase-file", "", "File holding the passphrase to derive the key from")
+	input := fs.String("input", "", "Input string to encrypt/decrypt, - or none to stream stdin")
+	inFile := fs.String("in-file", "", "File to encrypt/decrypt, streamed so it can be of any size")
+	outFile := fs.String("out-file", "", "File to write the result to instead of stdout")
+	inPlace := fs.Bool("in-place", false, "Replace -in-file with the result, after saving a copy with a .bak suffix")
+	inGlob := fs.String("in-glob", "", "Encrypt every file matching the pattern to a file with an .enc suffix next to it")
+	useHex := fs.Bool("hex", false, "Encode the ciphertext as hex, the default for -input and for -in-file printed to stdout, and recognized without the flag when decrypting")
+	useBase64 := fs.Bool("base64", false, "Encode the ciphertext as standard base64 instead of hex")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
 
 	// Check for required flags
 	if *generate {
-		generateKey()
-		return
+		if err := generateKey(stdout); err != nil {
+			fmt.Fprintf(stderr, "Error generating key: %v\n", err)
+			return 1
+		}
+		return 0
 	}
 
-	if *input == "" {
-		fmt.Println("Error: No input provided. Use -input flag.")
-		printUsage()
-		os.Exit(1)
+	if *input != "" && *inFile != "" {
+		fmt.Fprintln(stderr, "Error: -input and -in-file can't be used together.")
+		return 1
+	}
+	if *inGlob != "" && (*input != "" || *inFile != "" || *outFile != "" || *decryptMode) {
+		fmt.Fprintln(stderr, "Error: -in-glob can't be used with -input, -in-file, -out-file or -decrypt.")
+		return 1
+	}
+	if *inPlace && *inFile == "" {
+		fmt.Fprintln(stderr, "Error: -in-place requires -in-file.")
+		return 1
+	}
+	if *inPlace && (*outFile != "" || *useHex || *useBase64 || *passphrase || *passphraseFile != "") {
+		fmt.Fprintln(stderr, "Error: -in-place can't be used with -out-file, -hex, -base64 or a passphrase.")
+		return 1
+	}
+	if *useHex && *useBase64 {
+		fmt.Fprintln(stderr, "Error: -hex and -base64 can't be used together.")
+		return 1
 	}
+	var encoding string
+	switch {
+	case *useHex:
+		encoding = encodingHex
+	case *useBase64:
+		encoding = encodingBase64
+	}
+
+	sources := 0
+	for _, set := range []bool{*key != "", *keyFile != "", *keyEnv != "", *passphrase, *passphraseFile != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		fmt.Fprintln(stderr, "Error: only one of -key, -key-file, -key-env, -passphrase and -passphrase-file can be used.")
+		return 1
+	}
+
+	var ck cipherKey
+	var err error
+	if *passphrase || *passphraseFile != "" {
+		ck.passphrase, err = loadPassphrase(*passphraseFile, !*decryptMode)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	} else {
+		hexKey, err := loadKey(*key, *keyFile, *keyEnv)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		if hexKey == "" {
+			fmt.Fprintln(stderr, "Error: No key provided. Use -key, -key-file, -key-env or -passphrase flag or generate one with -generate-key.")
+			printUsage(stderr)
+			return 1
+		}
 
-	if *key == "" {
-		fmt.Println("Error: No key provided. Use -key flag or generate one with -generate-key.")
-		printUsage()
-		os.Exit(1)
+		// Create a new AES256 instance
+		ck.aes, err = cryptutil.NewAES256(hexKey)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error initializing encryption: %v\n", err)
+			return 1
+		}
 	}
 
-	// Create a new AES256 instance
-	aes, err := cryptutil.NewAES256(*key)
+	switch {
+	case *inGlob != "":
+		return encryptGlob(ck, *inGlob, encoding, stdout, stderr)
+	case *inPlace && *decryptMode:
+		err = cryptutil.DecryptFileInPlace(ck.aes, *inFile)
+	case *inPlace:
+		err = cryptutil.EncryptFileInPlace(ck.aes, *inFile)
+	case *inFile != "":
+		err = processFile(ck, *inFile, *outFile, *decryptMode, encoding, stdout)
+	case *input == "" || *input == "-":
+		err = processStream(ck, stdin, *outFile, *decryptMode, encoding, stdout)
+	default:
+		err = processString(ck, *input, *outFile, *decryptMode, encoding, stdout)
+	}
 	if err != nil {
-		fmt.Printf("Error initializing encryption: %v\n", err)
-		os.Exit(1)
+		if *decryptMode {
+			fmt.Fprintf(stderr, "Error decrypting: %v\n", err)
+		} else {
+			fmt.Fprintf(stderr, "Error encrypting: %v\n", err)
+		}
+		return 1
 	}
+	return 0
+}
 
-	// Process input based on mode
+// loadKey returns the hex key from the key source that is set, or an empty key when none is
+func loadKey(key, keyFile, keyEnv string) (string, error) {
 	switch {
-	case *decryptMode:
-		result, err := aes.DecryptHexToString(*input)
+	case keyFile != "":
+		data, err := os.ReadFile(keyFile)
 		if err != nil {
-			fmt.Printf("Error decrypting: %v\n", err)
-			os.Exit(1)
+			return "", fmt.Errorf("reading key file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case keyEnv != "":
+		value, ok := os.LookupEnv(keyEnv)
+		if !ok || strings.TrimSpace(value) == "" {
+			return "", fmt.Errorf("environment variable %s is not set", keyEnv)
 		}
-		fmt.Println("Decrypted result:", result)
+		return strings.TrimSpace(value), nil
+	default:
+		return key, nil
+	}
+}
+
+// encryptedSuffix is appended to the name of the files encrypted by -in-glob
+const encryptedSuffix = ".enc"
+
+// encryptGlob encrypts every regular file matching pattern, except the ones already encrypted, reporting the
+// result of each file. It returns the exit code, which is non-zero if any file failed.
+func encryptGlob(ck cipherKey, pattern, encoding string, stdout, stderr io.Writer) int {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: invalid pattern: %v\n", err)
+		return 1
+	}
+
+	encrypted, failed := 0, 0
+	for _, path := range matches {
+		if strings.HasSuffix(path, encryptedSuffix) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		if err := processFile(ck, path, path+encryptedSuffix, false, encoding, stdout); err != nil {
+			fmt.Fprintf(stderr, "FAILED %s: %v\n", path, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(stdout, "encrypted %s -> %s\n", path, path+encryptedSuffix)
+		encrypted++
+	}
+
+	if encrypted+failed == 0 {
+		fmt.Fprintf(stderr, "Error: no files to encrypt match %s\n", pattern)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%d encrypted, %d failed\n", encrypted, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// processString encrypts the input string to hex or base64, or decrypts it from them, and prints the result or
+// writes it to outFile when set. With a passphrase, the salt is encoded before the ciphertext, separated by a colon.
+func processString(ck cipherKey, input, outFile string, decrypt bool, encoding string, stdout io.Writer) error {
+	if encoding == "" {
+		encoding = encodingHex
+	}
+
+	var result string
+	var err error
+	if decrypt {
+		result, err = decryptString(ck, input, encoding)
+	} else {
+		result, err = encryptString(ck, input, encoding)
+	}
+	if err != nil {
+		return err
+	}
+
+	if outFile != "" {
+		return writeFile(outFile, func(w io.Writer) error {
+			_, err := io.WriteString(w, result)
+			return err
+		})
+	}
+	if decrypt {
+		fmt.Fprintln(stdout, "Decrypted result:", result)
+	} else {
+		fmt.Fprintf(stdout, "Encrypted result (%s): %s\n", encoding, result)
+	}
+	return nil
+}
+
+// encryptString encrypts input to a string in the given encoding, starting with the encoded salt and a colon
+// when the key is derived from a passphrase
+func encryptString(ck cipherKey, input, encoding string) (string, error) {
+	aes, salt, err := ck.forEncryption()
+	if err != nil {
+		return "", err
+	}
+
+	var result string
+	if encoding == encodingBase64 {
+		result, err = aes.EncryptStringToBase64(input)
+	} else {
+		result, err = aes.EncryptStringToHex(input)
+	}
+	if err != nil {
+		return "", err
+	}
+	if salt != nil {
+		result = encodeString(salt, encoding) + ":" + result
+	}
+	return result, nil
+}
+
+// decryptString decrypts a string produced by encryptString
+func decryptString(ck cipherKey, input, encoding string) (string, error) {
+	var salt []byte
+	if ck.passphrase != nil {
+		encodedSalt, ciphertext, found := strings.Cut(input, ":")
+		if !found {
+			return "", errors.New("missing salt, the input wasn't encrypted with a passphrase")
+		}
+		var err error
+		if salt, err = decodeString(encodedSalt, encoding); err != nil {
+			return "", fmt.Errorf("invalid salt: %w", err)
+		}
+		input = ciphertext
+	}
 
-	default: // Default to encrypt mode
-		result, err := aes.EncryptStringToHex(*input)
+	aes, err := ck.forDecryption(bytes.NewReader(salt))
+	if err != nil {
+		return "", err
+	}
+	if encoding == encodingBase64 {
+		return aes.DecryptBase64ToString(input)
+	}
+	return aes.DecryptHexToString(input)
+}
+
+// processFile streams the content of inFile through the cipher. The ciphertext of a file is binary unless an
+// encoding is given, except when it is printed to stdout where it defaults to hex. Decrypting recognizes hex
+// ciphertext without the encoding, so that a file holding that output decrypts as is.
+func processFile(ck cipherKey, inFile, outFile string, decrypt bool, encoding string, stdout io.Writer) error {
+	in, err := os.Open(inFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if encoding == "" && outFile == "" && !decrypt {
+		encoding = encodingHex
+	}
+	return processStream(ck, in, outFile, decrypt, encoding, stdout)
+}
+
+// processStream streams in through the cipher to outFile, or to stdout when outFile is empty. The ciphertext, read
+// when decrypting and written when encrypting, is in the given encoding or binary when it is empty, which is what
+// pipelines expect. With a passphrase, the ciphertext starts with the salt. The plaintext is always written as is.
+func processStream(ck cipherKey, in io.Reader, outFile string, decrypt bool, encoding string, stdout io.Writer) error {
+	process := func(w io.Writer) error {
+		if decrypt {
+			return decryptStream(ck, in, encoding, w)
+		}
+
+		aes, salt, err := ck.forEncryption()
 		if err != nil {
-			fmt.Printf("Error encrypting: %v\n", err)
-			os.Exit(1)
+			return err
+		}
+		if encoding == "" {
+			if _, err := w.Write(salt); err != nil {
+				return err
+			}
+			return aes.EncryptStream(w, in)
+		}
+
+		encoder := encodeWriter(w, encoding)
+		if _, err := encoder.Write(salt); err != nil {
+			return err
+		}
+		if err := aes.EncryptStream(encoder, in); err != nil {
+			return err
+		}
+		if err := encoder.Close(); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w)
+		return err
+	}
+
+	if outFile != "" {
+		return writeFile(outFile, process)
+	}
+	return process(stdout)
+}
+
+// maxStringCiphertext is the size up to which an encoded ciphertext is tried as the output of -input before the
+// stream format, far more than fits on a command line
+const maxStringCiphertext = 1 << 20
+
+// decryptStream decrypts the ciphertext read from in and writes the plaintext to w. Without an encoding, a
+// ciphertext made of hex digits only, as printed by default, is decoded as hex. An encoded ciphertext is either in
+// the stream format or, when it is small, in the format of -input, which is tried first.
+func decryptStream(ck cipherKey, in io.Reader, encoding string, w io.Writer) error {
+	buffered := bufio.NewReader(in)
+	if encoding == "" && looksHex(buffered) {
+		encoding = encodingHex
+	}
+
+	var ciphertext io.Reader = buffered
+	if encoding != "" {
+		head, err := io.ReadAll(io.LimitReader(buffered, maxStringCiphertext+1))
+		if err != nil {
+			return err
+		}
+		if len(head) <= maxStringCiphertext {
+			if plaintext, err := decryptString(ck, strings.TrimSpace(string(head)), encoding); err == nil {
+				_, err = io.WriteString(w, plaintext)
+				return err
+			}
+		}
+		ciphertext = io.MultiReader(bytes.NewReader(head), buffered)
+	}
+
+	r := decodeReader(ciphertext, encoding)
+	aes, err := ck.forDecryption(r)
+	if err != nil {
+		return err
+	}
+	return aes.DecryptStream(w, r)
+}
+
+// looksHex reports whether the start of r is made of hex digits, which binary ciphertext, starting with random
+// bytes, practically never is
+func looksHex(r *bufio.Reader) bool {
+	start, _ := r.Peek(64)
+	digits := 0
+	for _, b := range start {
+		switch {
+		case b >= '0' && b <= '9', b >= 'a' && b <= 'f', b >= 'A' && b <= 'F':
+			digits++
+		case b != ' ' && b != '\t' && b != '\r' && b != '\n':
+			return false
 		}
-		fmt.Println("Encrypted result (hex):", result)
 	}
+	return digits > 0
+}
+
+// Text encodings of the ciphertext
+const (
+	encodingHex    = "hex"
+	encodingBase64 = "base64"
+)
+
+// encodeString encodes data as a string in the given encoding
+func encodeString(data []byte, encoding string) string {
+	if encoding == encodingBase64 {
+		return base64.StdEncoding.EncodeToString(data)
+	}
+	return hex.EncodeToString(data)
+}
+
+// decodeString decodes a string in the given encoding
+func decodeString(s, encoding string) ([]byte, error) {
+	if encoding == encodingBase64 {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return hex.DecodeString(s)
+}
+
+// nopCloser adds a no-op Close to a writer
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// encodeWriter returns a writer encoding what is written to w, which must be closed to flush it
+func encodeWriter(w io.Writer, encoding string) io.WriteCloser {
+	if encoding == encodingBase64 {
+		return base64.NewEncoder(base64.StdEncoding, w)
+	}
+	return nopCloser{hex.NewEncoder(w)}
+}
+
+// decodeReader returns a reader decoding r, skipping line breaks and spaces around the encoded data, or r itself
+// when there is no encoding
+func decodeReader(r io.Reader, encoding string) io.Reader {
+	switch encoding {
+	case encodingHex:
+		return hex.NewDecoder(spaceSkipper{r})
+	case encodingBase64:
+		return base64.NewDecoder(base64.StdEncoding, spaceSkipper{r})
+	default:
+		return r
+	}
+}
+
+// spaceSkipper is a reader dropping the whitespace of the reader it wraps
+type spaceSkipper struct {
+	r io.Reader
+}
+
+func (s spaceSkipper) Read(p []byte) (int, error) {
+	for {
+		n, err := s.r.Read(p)
+		kept := 0
+		for _, b := range p[:n] {
+			if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+				p[kept] = b
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
+
+// writeFile creates path, readable only by the current user, and fills it with write. The file is removed if write
+// fails so that no partial result is left behind.
+func writeFile(path string, write func(w io.Writer) error) error {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := write(out); err != nil {
+		out.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
 }
 
 // generateKey creates and prints a new random AES-256 key
-func generateKey() {
-	key := make([]byte, 32) // 32 bytes = 256 bits
-	_, err := rand.Read(key)
+func generateKey(w io.Writer) error {
+	hexKey, err := cryptutil.GenerateKey()
 	if err != nil {
-		fmt.Printf("Error generating key: %v\n", err)
-		os.Exit(1)
+		return err
 	}
 
-	hexKey := hex.EncodeToString(key)
-	fmt.Println("Generated AES-256 key (save this securely):")
-	fmt.Println(hexKey)
+	fmt.Fprintln(w, "Generated AES-256 key (save this securely):")
+	fmt.Fprintln(w, hexKey)
+	return nil
 }
 
 // printUsage prints a more descriptive usage message
-func printUsage() {
-	fmt.Println("\nUsage examples:")
-	fmt.Println("  Generate a new key:")
-	fmt.Println("    go run main.go -generate-key")
-	fmt.Println("  Encrypt a string:")
-	fmt.Println("    go run main.go -key YOUR_KEY -input \"secret message\"")
-	fmt.Println("  Decrypt a hex string:")
-	fmt.Println("    go run main.go -decrypt -key YOUR_KEY -input ENCRYPTED_HEX_STRING")
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "\nUsage examples:")
+	fmt.Fprintln(w, "  Generate a new key:")
+	fmt.Fprintln(w, "    go run main.go -generate-key")
+	fmt.Fprintln(w, "  Encrypt a string:")
+	fmt.Fprintln(w, "    go run main.go -key YOUR_KEY -input \"secret message\"")
+	fmt.Fprintln(w, "  Decrypt a hex string:")
+	fmt.Fprintln(w, "    go run main.go -decrypt -key YOUR_KEY -input ENCRYPTED_HEX_STRING")
+	fmt.Fprintln(w, "  Encrypt a file:")
+	fmt.Fprintln(w, "    go run main.go -key YOUR_KEY -in-file secret.txt -out-file secret.txt.enc")
+	fmt.Fprintln(w, "  Decrypt a file:")
+	fmt.Fprintln(w, "    go run main.go -decrypt -key YOUR_KEY -in-file secret.txt.enc -out-file secret.txt")
+	fmt.Fprintln(w, "  Encrypt with a key from a file or an environment variable:")
+	fmt.Fprintln(w, "    go run main.go -key-file key.hex -input \"secret message\"")
+	fmt.Fprintln(w, "    go run main.go -key-env CRYPTUTIL_KEY -input \"secret message\"")
+	fmt.Fprintln(w, "  Encrypt a file with a key derived from a passphrase:")
+	fmt.Fprintln(w, "    go run main.go -passphrase -in-file secret.txt -out-file secret.txt.enc")
+	fmt.Fprintln(w, "  Encrypt a string to base64:")
+	fmt.Fprintln(w, "    go run main.go -base64 -key YOUR_KEY -input \"secret message\"")
+	fmt.Fprintln(w, "  Encrypt a file in place, keeping a secret.txt.bak copy:")
+	fmt.Fprintln(w, "    go run main.go -key YOUR_KEY -in-file secret.txt -in-place")
+	fmt.Fprintln(w, "  Encrypt every file of a directory to .enc files:")
+	fmt.Fprintln(w, "    go run main.go -key YOUR_KEY -in-glob 'secrets/*.txt'")
+	fmt.Fprintln(w, "  Encrypt stdin to stdout:")
+	fmt.Fprintln(w, "    cat secret.txt | go run main.go -key YOUR_KEY > secret.txt.enc")
 }
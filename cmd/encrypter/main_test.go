@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+// TestFileRoundTrip encrypts a file and decrypts it back through the command line.
+func TestFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "secret.bin")
+	encPath := filepath.Join(dir, "secret.bin.enc")
+	decPath := filepath.Join(dir, "secret.bin.dec")
+
+	plaintext := make([]byte, 200*1024+3)
+	_, err := rand.Read(plaintext)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(plainPath, plaintext, 0600))
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"-key", testKey, "-in-file", plainPath, "-out-file", encPath}, nil, &stdout, &stderr), stderr.String())
+	encrypted, err := os.ReadFile(encPath)
+	require.NoError(t, err)
+	require.NotContains(t, string(encrypted), string(plaintext[:64]))
+
+	require.Equal(t, 0, run([]string{"-decrypt", "-key", testKey, "-in-file", encPath, "-out-file", decPath}, nil, &stdout, &stderr), stderr.String())
+	decrypted, err := os.ReadFile(decPath)
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(plaintext, decrypted))
+
+	// Without -out-file the plaintext is printed as is
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"-decrypt", "-key", testKey, "-in-file", encPath}, nil, &stdout, &stderr), stderr.String())
+	require.True(t, bytes.Equal(plaintext, stdout.Bytes()))
+}
+
+// TestFileStdoutRoundTrip decrypts a file holding the hex ciphertext printed when encrypting a file to stdout.
+func TestFileStdoutRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "p.txt")
+	encPath := filepath.Join(dir, "c.txt")
+	require.NoError(t, os.WriteFile(plainPath, []byte("file secret\n"), 0600))
+
+	var encrypted, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"-key", testKey, "-in-file", plainPath}, nil, &encrypted, &stderr), stderr.String())
+	require.NoError(t, os.WriteFile(encPath, encrypted.Bytes(), 0600))
+
+	var decrypted bytes.Buffer
+	require.Equal(t, 0, run([]string{"-decrypt", "-key", testKey, "-in-file", encPath}, nil, &decrypted, &stderr), stderr.String())
+	require.Equal(t, "file secret\n", decrypted.String())
+}
+
+// TestFileDecryptFailure verifies that a failed decryption exits non-zero and leaves no output file.
+func TestFileDecryptFailure(t *testing.T) {
+	dir := t.TempDir()
+	encPath := filepath.Join(dir, "garbage.enc")
+	decPath := filepath.Join(dir, "garbage")
+	require.NoError(t, os.WriteFile(encPath, []byte("definitely not ciphertext"), 0600))
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 1, run([]string{"-decrypt", "-key", testKey, "-in-file", encPath, "-out-file", decPath}, nil, &stdout, &stderr))
+	require.Contains(t, stderr.String(), "Error decrypting")
+	require.NoFileExists(t, decPath)
+
+	require.Equal(t, 1, run([]string{"-key", testKey, "-input", "x", "-in-file", encPath}, nil, &stdout, &stderr))
+}
+
+// TestPipe drives the command with piped buffers, feeding the encrypted output back in to decrypt it.
+func TestPipe(t *testing.T) {
+	plaintext := []byte("cat secret | cryptutil -key ... | base64\n")
+
+	var encrypted, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"-key", testKey}, bytes.NewReader(plaintext), &encrypted, &stderr), stderr.String())
+	require.NotContains(t, encrypted.String(), "secret")
+
+	var decrypted bytes.Buffer
+	require.Equal(t, 0, run([]string{"-decrypt", "-key", testKey, "-input", "-"}, &encrypted, &decrypted, &stderr), stderr.String())
+	require.Equal(t, plaintext, decrypted.Bytes())
+
+	require.Equal(t, 1, run([]string{"-decrypt", "-key", testKey}, bytes.NewReader([]byte("garbage")), &decrypted, &stderr))
+}
+
+// TestInputThroughStdin decrypts the output of -input by piping it to the command, like any other ciphertext.
+func TestInputThroughStdin(t *testing.T) {
+	dir := t.TempDir()
+	passphraseFile := filepath.Join(dir, "passphrase")
+	require.NoError(t, os.WriteFile(passphraseFile, []byte("correct horse battery staple\n"), 0600))
+
+	for _, tc := range []struct {
+		encoding string
+		keyFlags []string
+		decrypt  []string
+	}{
+		{"hex", []string{"-key", testKey}, []string{"-hex"}},
+		{"hex", []string{"-key", testKey}, nil},
+		{"base64", []string{"-key", testKey}, []string{"-base64"}},
+		{"hex", []string{"-passphrase-file", passphraseFile}, []string{"-hex"}},
+	} {
+		var stdout, stderr bytes.Buffer
+		args := append([]string{"-" + tc.encoding, "-input", "hello"}, tc.keyFlags...)
+		require.Equal(t, 0, run(args, nil, &stdout, &stderr), stderr.String())
+		encrypted, found := strings.CutPrefix(strings.TrimSpace(stdout.String()), "Encrypted result ("+tc.encoding+"): ")
+		require.True(t, found, stdout.String())
+
+		stdout.Reset()
+		args = append(append([]string{"-decrypt"}, tc.decrypt...), tc.keyFlags...)
+		require.Equal(t, 0, run(args, strings.NewReader(encrypted+"\n"), &stdout, &stderr), stderr.String())
+		require.Equal(t, "hello", stdout.String(), args)
+	}
+}
+
+// TestBase64 verifies that base64 ciphertext round trips through the command line, for strings and streams.
+func TestBase64(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"-base64", "-key", testKey, "-input", "secret message"}, nil, &stdout, &stderr), stderr.String())
+	encoded, found := strings.CutPrefix(strings.TrimSpace(stdout.String()), "Encrypted result (base64): ")
+	require.True(t, found, stdout.String())
+	_, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"-decrypt", "-base64", "-key", testKey, "-input", encoded}, nil, &stdout, &stderr), stderr.String())
+	require.Equal(t, "Decrypted result: secret message\n", stdout.String())
+
+	var encrypted bytes.Buffer
+	require.Equal(t, 0, run([]string{"-base64", "-key", testKey}, strings.NewReader("piped secret"), &encrypted, &stderr), stderr.String())
+	_, err = base64.StdEncoding.DecodeString(strings.TrimSpace(encrypted.String()))
+	require.NoError(t, err)
+
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"-decrypt", "-base64", "-key", testKey}, &encrypted, &stdout, &stderr), stderr.String())
+	require.Equal(t, "piped secret", stdout.String())
+
+	require.Equal(t, 1, run([]string{"-hex", "-base64", "-key", testKey, "-input", "x"}, nil, &stdout, &stderr))
+}
+
+// TestKeySources verifies that the key can be read from a file or an environment variable, but from only one source.
+func TestKeySources(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"-key", testKey, "-input", "secret message"}, nil, &stdout, &stderr), stderr.String())
+	encrypted, found := strings.CutPrefix(strings.TrimSpace(stdout.String()), "Encrypted result (hex): ")
+	require.True(t, found, stdout.String())
+
+	keyFile := filepath.Join(t.TempDir(), "key.hex")
+	require.NoError(t, os.WriteFile(keyFile, []byte(testKey+"\n"), 0600))
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"-decrypt", "-key-file", keyFile, "-input", encrypted}, nil, &stdout, &stderr), stderr.String())
+	require.Equal(t, "Decrypted result: secret message\n", stdout.String())
+
+	t.Setenv("CRYPTUTIL_TEST_KEY", testKey)
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"-decrypt", "-key-env", "CRYPTUTIL_TEST_KEY", "-input", encrypted}, nil, &stdout, &stderr), stderr.String())
+	require.Equal(t, "Decrypted result: secret message\n", stdout.String())
+
+	stderr.Reset()
+	require.Equal(t, 1, run([]string{"-key-env", "CRYPTUTIL_TEST_MISSING_KEY", "-input", "x"}, nil, &stdout, &stderr))
+	require.Contains(t, stderr.String(), "CRYPTUTIL_TEST_MISSING_KEY")
+
+	stderr.Reset()
+	require.Equal(t, 1, run([]string{"-key", testKey, "-key-env", "CRYPTUTIL_TEST_KEY", "-input", "x"}, nil, &stdout, &stderr))
+	require.Contains(t, stderr.String(), "only one of")
+}
+
+// TestGlob verifies that every matching file gets an .enc counterpart that decrypts back to it.
+func TestGlob(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"a.txt": "first secret", "b.txt": "second secret", "c.txt": "third secret"}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ignored.json"), []byte("{}"), 0600))
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"-key", testKey, "-in-glob", filepath.Join(dir, "*.txt")}, nil, &stdout, &stderr), stderr.String())
+	require.Contains(t, stdout.String(), "3 encrypted, 0 failed")
+	require.NoFileExists(t, filepath.Join(dir, "ignored.json.enc"))
+
+	for name, content := range files {
+		var decrypted bytes.Buffer
+		encPath := filepath.Join(dir, name+".enc")
+		require.Equal(t, 0, run([]string{"-decrypt", "-key", testKey, "-in-file", encPath}, nil, &decrypted, &stderr), stderr.String())
+		require.Equal(t, content, decrypted.String())
+	}
+
+	// A file whose output can't be written fails the run without stopping the other files, and the .enc files
+	// matched by the pattern are skipped
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "ignored.json.enc"), 0700))
+	stdout.Reset()
+	stderr.Reset()
+	require.Equal(t, 1, run([]string{"-key", testKey, "-in-glob", filepath.Join(dir, "*")}, nil, &stdout, &stderr))
+	require.Contains(t, stdout.String(), "3 encrypted, 1 failed")
+	require.Contains(t, stderr.String(), "FAILED "+filepath.Join(dir, "ignored.json"))
+
+	require.Equal(t, 1, run([]string{"-key", testKey, "-in-glob", filepath.Join(dir, "*.missing")}, nil, &stdout, &stderr))
+}
+
+// TestPassphrase verifies that data encrypted with a passphrase decrypts with the same passphrase only.
+func TestPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	passphraseFile := filepath.Join(dir, "passphrase")
+	require.NoError(t, os.WriteFile(passphraseFile, []byte("correct horse battery staple\n"), 0600))
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"-passphrase-file", passphraseFile, "-input", "secret message"}, nil, &stdout, &stderr), stderr.String())
+	encrypted, found := strings.CutPrefix(strings.TrimSpace(stdout.String()), "Encrypted result (hex): ")
+	require.True(t, found, stdout.String())
+
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"-decrypt", "-passphrase-file", passphraseFile, "-input", encrypted}, nil, &stdout, &stderr), stderr.String())
+	require.Equal(t, "Decrypted result: secret message\n", stdout.String())
+
+	// Streams carry the salt too
+	var stream bytes.Buffer
+	require.Equal(t, 0, run([]string{"-passphrase-file", passphraseFile}, strings.NewReader("piped secret"), &stream, &stderr), stderr.String())
+	streamCopy := bytes.Clone(stream.Bytes())
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"-decrypt", "-passphrase-file", passphraseFile}, &stream, &stdout, &stderr), stderr.String())
+	require.Equal(t, "piped secret", stdout.String())
+
+	wrongFile := filepath.Join(dir, "wrong")
+	require.NoError(t, os.WriteFile(wrongFile, []byte("incorrect horse battery staple"), 0600))
+	require.Equal(t, 1, run([]string{"-decrypt", "-passphrase-file", wrongFile, "-input", encrypted}, nil, &stdout, &stderr))
+	require.Equal(t, 1, run([]string{"-decrypt", "-passphrase-file", wrongFile}, bytes.NewReader(streamCopy), &stdout, &stderr))
+
+	stderr.Reset()
+	require.Equal(t, 1, run([]string{"-key", testKey, "-passphrase-file", passphraseFile, "-input", "x"}, nil, &stdout, &stderr))
+	require.Contains(t, stderr.String(), "only one of")
+}
+
+// TestInPlace encrypts a file in place, checks the backup and decrypts it back to the original bytes.
+func TestInPlace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	original := []byte(`{"password": "hunter2"}`)
+	require.NoError(t, os.WriteFile(path, original, 0600))
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"-key", testKey, "-in-file", path, "-in-place"}, nil, &stdout, &stderr), stderr.String())
+	backup, err := os.ReadFile(path + ".bak")
+	require.NoError(t, err)
+	require.Equal(t, original, backup)
+	encrypted, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotEqual(t, original, encrypted)
+
+	// The existing backup blocks the decryption until it is moved away
+	stderr.Reset()
+	require.Equal(t, 1, run([]string{"-decrypt", "-key", testKey, "-in-file", path, "-in-place"}, nil, &stdout, &stderr))
+	require.Contains(t, stderr.String(), "backup file already exists")
+
+	require.NoError(t, os.Remove(path+".bak"))
+	require.Equal(t, 0, run([]string{"-decrypt", "-key", testKey, "-in-file", path, "-in-place"}, nil, &stdout, &stderr), stderr.String())
+	decrypted, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, original, decrypted)
+
+	require.Equal(t, 1, run([]string{"-key", testKey, "-input", "x", "-in-place"}, nil, &stdout, &stderr))
+	require.Equal(t, 1, run([]string{"-key", testKey, "-in-file", path, "-in-place", "-base64"}, nil, &stdout, &stderr))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/catalogfi/tools/pkg/cryptutil"
+	"golang.org/x/term"
+)
+
+// cipherKey provides the cipher of the command, either built from a hex key or derived from a passphrase. A key
+// derived from a passphrase uses a new salt for every encryption, stored before the ciphertext so that the same
+// key can be derived again for decryption.
+type cipherKey struct {
+	aes        *cryptutil.AES256
+	passphrase []byte
+}
+
+// forEncryption returns the cipher to encrypt with, and the salt to store before the ciphertext when the key is
+// derived from a passphrase
+func (ck cipherKey) forEncryption() (*cryptutil.AES256, []byte, error) {
+	if ck.passphrase == nil {
+		return ck.aes, nil, nil
+	}
+
+	salt, err := cryptutil.GenerateSalt()
+	if err != nil {
+		return nil, nil, err
+	}
+	aes, err := cryptutil.NewAES256FromPassphrase(ck.passphrase, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aes, salt, nil
+}
+
+// forDecryption returns the cipher to decrypt with, first reading the salt from r when the key is derived from a
+// passphrase
+func (ck cipherKey) forDecryption(r io.Reader) (*cryptutil.AES256, error) {
+	if ck.passphrase == nil {
+		return ck.aes, nil
+	}
+
+	salt := make([]byte, cryptutil.SaltSize)
+	if _, err := io.ReadFull(r, salt); err != nil {
+		return nil, fmt.Errorf("reading salt: %w", err)
+	}
+	return cryptutil.NewAES256FromPassphrase(ck.passphrase, salt)
+}
+
+// loadPassphrase reads the passphrase from passphraseFile, or prompts for it on the terminal when passphraseFile
+// is empty. With confirm, the prompt asks for the passphrase twice to catch typos before encrypting.
+func loadPassphrase(passphraseFile string, confirm bool) ([]byte, error) {
+	if passphraseFile != "" {
+		data, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading passphrase file: %w", err)
+		}
+		passphrase := []byte(strings.TrimRight(string(data), "\r\n"))
+		if len(passphrase) == 0 {
+			return nil, errors.New("passphrase file is empty")
+		}
+		return passphrase, nil
+	}
+
+	// Prompt on the terminal rather than stdin, which may hold the input to encrypt
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, errors.New("no terminal to prompt for the passphrase, use -passphrase-file")
+	}
+	defer tty.Close()
+
+	passphrase, err := promptPassphrase(tty, "Passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	if confirm {
+		again, err := promptPassphrase(tty, "Confirm passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(passphrase, again) {
+			return nil, errors.New("passphrases don't match")
+		}
+	}
+	return passphrase, nil
+}
+
+// promptPassphrase prints prompt to the terminal and reads a line without echoing it
+func promptPassphrase(tty *os.File, prompt string) ([]byte, error) {
+	fmt.Fprint(tty, prompt)
+	passphrase, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return nil, fmt.Errorf("reading passphrase: %w", err)
+	}
+	return passphrase, nil
+}
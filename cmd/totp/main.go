@@ -1,62 +1,241 @@
 package main
 
 import (
-	"bytes"
+	"errors"
 	"flag"
 	"fmt"
-	"image/png"
+	"io"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/catalogfi/tools/pkg/totp"
 	"github.com/pquerna/otp"
-	"github.com/pquerna/otp/totp"
 )
 
+// errInvalidCode is returned by the validate command when the code is rejected
+var errInvalidCode = errors.New("invalid code")
+
+const usage = `Usage:
+  totp [generate] [flags]   generate a new secret and its QR code
+  totp code -secret SECRET  print the current code of a secret
+  totp validate -secret SECRET -code CODE
+                            check a code against a secret
+
+Run a command with -h to list its flags.
+`
+
 func main() {
-	accountName := flag.String("account", "", "account name")
-	flag.Parse()
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command given by args and returns the exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	command := "generate"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	var err error
+	switch command {
+	case "generate":
+		err = runGenerate(args, stdout, stderr)
+	case "code":
+		err = runCode(args, stdout, stderr)
+	case "validate":
+		err = runValidate(args, stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", command, usage)
+		return 2
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if errors.Is(err, errInvalidCode) {
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// codeFlags holds the flags describing the parameters of a secret
+type codeFlags struct {
+	period    *uint
+	digits    *int
+	algorithm *string
+}
+
+// addCodeFlags registers the flags describing the parameters of a secret
+func addCodeFlags(fs *flag.FlagSet) codeFlags {
+	return codeFlags{
+		period:    fs.Uint("period", 30, "seconds each code stays valid"),
+		digits:    fs.Int("digits", 6, "number of digits of the codes, 6 or 8"),
+		algorithm: fs.String("algorithm", "SHA1", "HMAC algorithm, one of SHA1, SHA256 or SHA512"),
+	}
+}
+
+// options converts the flags into totp options
+func (f codeFlags) options() ([]totp.Option, error) {
+	if *f.period == 0 {
+		return nil, errors.New("-period must be greater than zero")
+	}
+	if *f.digits != int(otp.DigitsSix) && *f.digits != int(otp.DigitsEight) {
+		return nil, fmt.Errorf("-digits must be 6 or 8, got %d", *f.digits)
+	}
+	alg, err := parseAlgorithm(*f.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return []totp.Option{
+		totp.WithPeriod(*f.period),
+		totp.WithDigits(otp.Digits(*f.digits)),
+		totp.WithAlgorithm(alg),
+	}, nil
+}
+
+// runGenerate generates a new secret and displays it
+func runGenerate(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	accountName := fs.String("account", "", "account name")
+	issuer := fs.String("issuer", "moji", "issuer shown by the authenticator app")
+	params := addCodeFlags(fs)
+	ascii := fs.Bool("ascii", false, "print the QR code to the terminal instead of writing qr-code.png")
+	invert := fs.Bool("invert", false, "with -ascii, invert the QR code for terminals with a light background")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts, err := params.options()
+	if err != nil {
+		return err
+	}
+	key, err := totp.GenerateSecret(*issuer, *accountName, opts...)
+	if err != nil {
+		return err
+	}
+	if *ascii {
+		return DisplayASCII(stdout, key, *invert)
+	}
+	return Display(stdout, key)
+}
 
-	key, err := GenerateSecret(*accountName)
+// runCode prints the current code of a secret and how long it stays valid
+func runCode(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("code", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	secret := fs.String("secret", "", "base32 secret")
+	params := addCodeFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *secret == "" {
+		return errors.New("-secret is required")
+	}
+
+	opts, err := params.options()
 	if err != nil {
-		panic(err)
+		return err
 	}
-	if err := Display(key); err != nil {
-		panic(err)
+	now := time.Now()
+	code, err := totp.GenerateCode(*secret, now, opts...)
+	if err != nil {
+		return err
 	}
+
+	period := int64(*params.period)
+	remaining := period - now.Unix()%period
+	fmt.Fprintf(stdout, "%s (valid for %ds)\n", code, remaining)
+	return nil
 }
 
-// GenerateSecret generates a new random secret key with optional parameters
-func GenerateSecret(accountName string) (*otp.Key, error) {
-	return totp.Generate(totp.GenerateOpts{
-		Issuer:      "moji",
-		AccountName: accountName,
-		Period:      30,
-		Digits:      otp.DigitsSix,
-		Algorithm:   otp.AlgorithmSHA1,
-	})
+// runValidate checks a code against a secret, allowing skew periods of clock drift
+func runValidate(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	secret := fs.String("secret", "", "base32 secret")
+	code := fs.String("code", "", "code to validate")
+	skew := fs.Uint("skew", 1, "number of periods before and after the current one to accept")
+	params := addCodeFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *secret == "" || *code == "" {
+		return errors.New("-secret and -code are required")
+	}
+
+	opts, err := params.options()
+	if err != nil {
+		return err
+	}
+	if !totp.ValidateWithWindow(*secret, *code, *skew, opts...) {
+		fmt.Fprintln(stdout, "invalid")
+		return errInvalidCode
+	}
+	fmt.Fprintln(stdout, "valid")
+	return nil
 }
 
 // Display the key in a qr code image
-func Display(key *otp.Key) error {
-	var buf bytes.Buffer
-	img, err := key.Image(200, 200)
+func Display(w io.Writer, key *otp.Key) error {
+	img, err := totp.RenderPNG(key, 200)
 	if err != nil {
 		return err
 	}
-	if err := png.Encode(&buf, img); err != nil {
+
+	printKey(w, key)
+	fmt.Fprintln(w, "Writing PNG to qr-code.png....")
+	if err := os.WriteFile("qr-code.png", img, 0644); err != nil {
 		return err
 	}
-	key.URL()
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Please add your TOTP to your OTP Application now!")
+	fmt.Fprintln(w, "")
+	return nil
+}
 
-	fmt.Printf("Issuer:       %s\n", key.Issuer())
-	fmt.Printf("Account Name: %s\n", key.AccountName())
-	fmt.Printf("Secret:       %s\n", key.Secret())
-	fmt.Printf("URL   :       %s\n", key.URL())
-	fmt.Println("Writing PNG to qr-code.png....")
-	if err := os.WriteFile("qr-code.png", buf.Bytes(), 0644); err != nil {
+// DisplayASCII prints the key and its qr code to the terminal
+func DisplayASCII(w io.Writer, key *otp.Key, inverted bool) error {
+	render := totp.RenderASCII
+	if inverted {
+		render = totp.RenderASCIIInverted
+	}
+	code, err := render(key)
+	if err != nil {
 		return err
 	}
-	fmt.Println("")
-	fmt.Println("Please add your TOTP to your OTP Application now!")
-	fmt.Println("")
+
+	printKey(w, key)
+	fmt.Fprintln(w, "")
+	fmt.Fprint(w, code)
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Please add your TOTP to your OTP Application now!")
+	fmt.Fprintln(w, "")
 	return nil
 }
+
+// printKey prints the details of the key
+func printKey(w io.Writer, key *otp.Key) {
+	fmt.Fprintf(w, "Issuer:       %s\n", key.Issuer())
+	fmt.Fprintf(w, "Account Name: %s\n", key.AccountName())
+	fmt.Fprintf(w, "Secret:       %s\n", key.Secret())
+	fmt.Fprintf(w, "URL   :       %s\n", key.URL())
+}
+
+// parseAlgorithm returns the HMAC algorithm with the given name
+func parseAlgorithm(name string) (otp.Algorithm, error) {
+	switch strings.ToUpper(name) {
+	case "SHA1":
+		return otp.AlgorithmSHA1, nil
+	case "SHA256":
+		return otp.AlgorithmSHA256, nil
+	case "SHA512":
+		return otp.AlgorithmSHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported algorithm %q, expected SHA1, SHA256 or SHA512", name)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/catalogfi/tools/pkg/totp"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ"
+
+// TestCode verifies the output and exit code of the code command.
+func TestCode(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"code", "-secret", testSecret}, &stdout, &stderr))
+	match := regexp.MustCompile(`^(\d{6}) \(valid for (\d+)s\)\n$`).FindStringSubmatch(stdout.String())
+	require.NotNil(t, match, stdout.String())
+	require.True(t, totp.ValidateWithWindow(testSecret, match[1], 1))
+
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"code", "-secret", testSecret, "-digits", "8"}, &stdout, &stderr))
+	require.Regexp(t, `^\d{8} `, stdout.String())
+
+	for _, args := range [][]string{
+		{"code"},
+		{"code", "-secret", "not base32!"},
+		{"code", "-secret", testSecret, "-period", "0"},
+	} {
+		stdout.Reset()
+		stderr.Reset()
+		require.Equal(t, 1, run(args, &stdout, &stderr), args)
+		require.Empty(t, stdout.String(), args)
+		require.Contains(t, stderr.String(), "error: ", args)
+	}
+}
+
+// TestZeroPeriod verifies that every command rejects a zero period instead of dividing by it.
+func TestZeroPeriod(t *testing.T) {
+	for _, args := range [][]string{
+		{"generate", "-period", "0"},
+		{"validate", "-secret", testSecret, "-code", "123456", "-period", "0"},
+	} {
+		var stdout, stderr bytes.Buffer
+		require.Equal(t, 1, run(args, &stdout, &stderr), args)
+		require.Contains(t, stderr.String(), "-period must be greater than zero", args)
+	}
+}
+
+// TestInvalidDigits verifies that every command rejects a number of digits other than 6 or 8.
+func TestInvalidDigits(t *testing.T) {
+	for _, digits := range []string{"-1", "0", "7"} {
+		for _, args := range [][]string{
+			{"generate", "-digits", digits},
+			{"code", "-secret", testSecret, "-digits", digits},
+			{"validate", "-secret", testSecret, "-code", "123456", "-digits", digits},
+		} {
+			var stdout, stderr bytes.Buffer
+			require.Equal(t, 1, run(args, &stdout, &stderr), args)
+			require.Contains(t, stderr.String(), "-digits must be 6 or 8", args)
+		}
+	}
+}
+
+// TestValidate verifies the exit code and output of the validate command.
+func TestValidate(t *testing.T) {
+	code, err := totp.GenerateCode(testSecret, time.Now())
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"validate", "-secret", testSecret, "-code", code}, &stdout, &stderr))
+	require.Equal(t, "valid\n", stdout.String())
+
+	stale, err := totp.GenerateCode(testSecret, time.Now().Add(-5*time.Minute))
+	require.NoError(t, err)
+
+	stdout.Reset()
+	require.Equal(t, 1, run([]string{"validate", "-secret", testSecret, "-code", stale}, &stdout, &stderr))
+	require.Equal(t, "invalid\n", stdout.String())
+
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"validate", "-secret", testSecret, "-code", stale, "-skew", "12"}, &stdout, &stderr))
+	require.Equal(t, "valid\n", stdout.String())
+}
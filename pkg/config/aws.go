@@ -0,0 +1,90 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// AWSPrefix is used for secrets stored in AWS Secrets Manager, e.g. "#AWS:prod/db/password"
+// returns the whole secret string while "#AWS:prod/db#password" returns the "password" key
+// of a JSON secret
+const AWSPrefix = "#AWS:"
+
+// SecretsManagerAPI is the subset of the Secrets Manager client used by AWSSecretResolver
+type SecretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// AWSSecretResolver fetches secrets from AWS Secrets Manager
+type AWSSecretResolver struct {
+	// Client is the Secrets Manager client. When nil, a client is created on first use with
+	// region and credentials taken from the standard AWS environment.
+	Client SecretsManagerAPI
+
+	mu sync.Mutex
+}
+
+// Resolve fetches the secret referenced by "<name>" or "<name>#<json key>"
+func (a *AWSSecretResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	name, key, hasKey := strings.Cut(ref, "#")
+	if name == "" || (hasKey && key == "") {
+		return "", fmt.Errorf("invalid aws secret reference %q, expected <name> or <name>#<key>", ref)
+	}
+
+	client, err := a.client(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: &name})
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return "", fmt.Errorf("aws secret %s %w: %w", name, ErrNotFound, err)
+		}
+		return "", fmt.Errorf("failed to fetch aws secret %s: %w", name, err)
+	}
+	if out.SecretString == nil {
+		return "", fmt.Errorf("aws secret %s has no string value", name)
+	}
+	if !hasKey {
+		return *out.SecretString, nil
+	}
+
+	var values map[string]any
+	if err := json.Unmarshal([]byte(*out.SecretString), &values); err != nil {
+		return "", fmt.Errorf("aws secret %s is not a JSON object: %w", name, err)
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("key %s in aws secret %s %w", key, name, ErrNotFound)
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("key %s in aws secret %s is not a string", key, name)
+	}
+	return str, nil
+}
+
+// client returns the configured client, loading one from the environment if necessary
+func (a *AWSSecretResolver) client(ctx context.Context) (SecretsManagerAPI, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.Client == nil {
+		cfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load aws config: %w", err)
+		}
+		a.Client = secretsmanager.NewFromConfig(cfg)
+	}
+	return a.Client, nil
+}
@@ -0,0 +1,74 @@
+package config_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/catalogfi/tools/pkg/config"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// stubSecretsManager serves secrets from an in-memory map
+type stubSecretsManager struct {
+	secrets map[string]string
+}
+
+func (s *stubSecretsManager) GetSecretValue(_ context.Context, params *secretsmanager.GetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	secret, ok := s.secrets[*params.SecretId]
+	if !ok {
+		return nil, errors.New("ResourceNotFoundException")
+	}
+	return &secretsmanager.GetSecretValueOutput{Name: params.SecretId, SecretString: &secret}, nil
+}
+
+var _ = Describe("AWS Secrets Manager", func() {
+	type AWSConfig struct {
+		Password string
+		Username string
+	}
+
+	var parser *config.Parser
+
+	BeforeEach(func() {
+		parser = config.NewParser("")
+		parser.RegisterResolver(config.AWSPrefix, &config.AWSSecretResolver{
+			Client: &stubSecretsManager{secrets: map[string]string{
+				"prod/db/password": "s3cret",
+				"prod/db":          `{"username":"admin","password":"json-s3cret"}`,
+			}},
+		})
+	})
+
+	It("should resolve a plain secret", func() {
+		conf := AWSConfig{Password: config.AWSPrefix + "prod/db/password"}
+		Expect(parser.ProcessStruct(&conf)).Should(Succeed())
+		Expect(conf.Password).To(Equal("s3cret"))
+	})
+
+	It("should resolve a key within a JSON secret", func() {
+		conf := AWSConfig{
+			Username: config.AWSPrefix + "prod/db#username",
+			Password: config.AWSPrefix + "prod/db#password",
+		}
+		Expect(parser.ProcessStruct(&conf)).Should(Succeed())
+		Expect(conf.Username).To(Equal("admin"))
+		Expect(conf.Password).To(Equal("json-s3cret"))
+	})
+
+	It("should return an error naming the missing secret", func() {
+		conf := AWSConfig{Password: config.AWSPrefix + "prod/missing"}
+		err := parser.ProcessStruct(&conf)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("prod/missing"))
+	})
+
+	It("should return an error when the JSON key is missing", func() {
+		conf := AWSConfig{Password: config.AWSPrefix + "prod/db#token"}
+		err := parser.ProcessStruct(&conf)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("token"))
+	})
+})
@@ -1,28 +1,196 @@
 package config
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-func LoadFromFile(filePath, secret string, target interface{}) error {
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return fmt.Errorf("file not exists")
+// AppEnvVar is the environment variable naming the environment the application runs in, such
+// as "production", which selects the override file of LoadWithEnvOverride
+const AppEnvVar = "APP_ENV"
+
+// ErrUnsupportedFormat is returned by LoadAuto for files whose extension isn't a known format
+var ErrUnsupportedFormat = errors.New("unsupported config format")
+
+// Validator is implemented by config types that can check their own values. The loaders call
+// Validate on the target after environment references are resolved and return its error as is.
+// Declarative rules such as struct tags can be checked as well, see WithStructValidator.
+type Validator interface {
+	Validate() error
+}
+
+func LoadFromFile(filePath, secret string, target interface{}, opts ...Options) error {
+	return LoadFromFileContext(context.Background(), filePath, secret, target, opts...)
+}
+
+// LoadFromFileContext is like LoadFromFile, bounding the fetches from remote secret stores
+// such as Vault or AWS Secrets Manager by ctx
+func LoadFromFileContext(ctx context.Context, filePath, secret string, target any, opts ...Options) error {
+	file, err := readFile(filePath)
+	if err != nil {
+		return err
+	}
+	return LoadFromBytesContext(ctx, file, secret, target, opts...)
+}
+
+// LoadAuto loads the config file with the loader matching its extension: LoadFromFile for
+// .json, LoadFromYAML for .yaml and .yml, and LoadFromTOML for .toml. The extension is matched
+// case-insensitively, and any other one fails with ErrUnsupportedFormat.
+func LoadAuto(filePath, secret string, target any, opts ...Options) error {
+	switch ext := strings.ToLower(filepath.Ext(filePath)); ext {
+	case ".json":
+		return LoadFromFile(filePath, secret, target, opts...)
+	case ".yaml", ".yml":
+		return LoadFromYAML(filePath, secret, target, opts...)
+	case ".toml":
+		return LoadFromTOML(filePath, secret, target, opts...)
+	default:
+		return fmt.Errorf("%w %q of %s", ErrUnsupportedFormat, ext, filePath)
 	}
-	file, err := os.ReadFile(filePath)
+}
+
+// LoadWithEnvOverride loads the config file at basePath and merges the override file of the
+// environment named by AppEnvVar on top of it, see LoadAndMerge. The override file sits next to
+// the base file and inserts the environment before the extension, so with APP_ENV=production
+// config.json is overridden by config.production.json. Only the base file is loaded when
+// AppEnvVar is unset or the override file doesn't exist.
+func LoadWithEnvOverride(basePath, secret string, target any, opts ...Options) error {
+	paths := []string{basePath}
+	if env := os.Getenv(AppEnvVar); env != "" {
+		ext := filepath.Ext(basePath)
+		overridePath := strings.TrimSuffix(basePath, ext) + "." + env + ext
+		if _, err := os.Stat(overridePath); err == nil {
+			paths = append(paths, overridePath)
+		}
+	}
+	return LoadAndMerge(paths, secret, target, opts...)
+}
+
+// LoadAndMerge loads several config files into the same target in order, so values in later
+// files override the ones from earlier files, and resolves environment references once at the
+// end. Files are JSON unless their extension is one of the YAML or TOML ones, see LoadAuto.
+//
+// Merging follows json.Unmarshal semantics on an existing value, which makes it deep for
+// objects and shallow for everything else:
+//   - nested structs are merged field by field, keys absent from a later file keep their value
+//   - maps are merged key by key, with later files replacing the value of an existing key
+//   - slices and arrays are replaced as a whole
+//   - a key present in a later file always wins, even when its value is zero such as "" or 0,
+//     and an explicit null resets pointers, maps and slices to nil
+func LoadAndMerge(paths []string, secret string, target any, opts ...Options) error {
+	raw := make(rawStrings)
+	for _, filePath := range paths {
+		file, err := readFile(filePath)
+		if err != nil {
+			return err
+		}
+		if file, err = toJSON(filePath, file); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", filePath, err)
+		}
+		if err := unmarshalJSON(file, target, opts, raw); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", filePath, err)
+		}
+	}
+
+	return resolve(context.Background(), secret, target, opts, raw)
+}
+
+// LoadFromReader reads the whole reader and loads the JSON config it contains into target
+func LoadFromReader(r io.Reader, secret string, target any, opts ...Options) error {
+	data, err := io.ReadAll(r)
 	if err != nil {
 		return err
 	}
-	if err := json.Unmarshal(file, target); err != nil {
+	return LoadFromBytes(data, secret, target, opts...)
+}
+
+// LoadFromBytes loads the JSON config in data into target and resolves environment references
+func LoadFromBytes(data []byte, secret string, target any, opts ...Options) error {
+	return LoadFromBytesContext(context.Background(), data, secret, target, opts...)
+}
+
+// LoadFromBytesContext is like LoadFromBytes, bounding the fetches from remote secret stores
+// such as Vault or AWS Secrets Manager by ctx
+func LoadFromBytesContext(ctx context.Context, data []byte, secret string, target any, opts ...Options) error {
+	raw := make(rawStrings)
+	if err := unmarshalJSON(data, target, opts, raw); err != nil {
 		return err
 	}
+	return resolve(ctx, secret, target, opts, raw)
+}
+
+// unmarshalJSON decodes data into target, stripping comments when they are allowed and
+// rejecting unknown fields in strict mode. The strings given for fields that encoding/json can't
+// decode them into are recorded in raw instead, for resolve to parse.
+func unmarshalJSON(data []byte, target any, opts []Options, raw rawStrings) error {
+	o := applyOptions(opts)
+	if o.comments {
+		var err error
+		if data, err = stripJSONC(data); err != nil {
+			return err
+		}
+	}
+	data = extractRawStrings(data, target, raw)
+	if !o.strict {
+		return json.Unmarshal(data, target)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("unexpected data after the top-level value")
+	}
+	return nil
+}
 
+// resolve resolves the environment references in an unmarshalled target, along with the raw
+// strings set aside while unmarshalling it, and validates it
+func resolve(ctx context.Context, secret string, target any, opts []Options, raw rawStrings) error {
 	// Parse the file when it contains confidential values can only be fetched from ENV
-	parser := NewParser(secret)
-	if err := parser.ProcessStruct(target); err != nil {
+	parser := NewParser(secret, opts...)
+	if err := parser.processStruct(ctx, target, raw); err != nil {
 		return err
 	}
 
+	if structValidator := parser.options().structValidator; structValidator != nil {
+		if err := structValidator.Struct(target); err != nil {
+			return err
+		}
+	}
+
+	if validator, ok := target.(Validator); ok {
+		return validator.Validate()
+	}
 	return nil
 }
+
+// toJSON converts the content of a YAML or TOML file, according to its extension, into JSON.
+// The content of other files is returned as is.
+func toJSON(filePath string, data []byte) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		return yamlToJSON(data)
+	case ".toml":
+		return tomlToJSON(data)
+	default:
+		return data, nil
+	}
+}
+
+// readFile reads the whole file, returning a descriptive error when it does not exist
+func readFile(filePath string) ([]byte, error) {
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("file not exists")
+	}
+	return os.ReadFile(filePath)
+}
@@ -1,9 +1,14 @@
 package config_test
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/catalogfi/tools/pkg/config"
+	"github.com/go-playground/validator/v10"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -17,6 +22,19 @@ type Config struct {
 	} `json:"bar"`
 }
 
+type ServerConfig struct {
+	Port int `json:"port"`
+}
+
+var errInvalidPort = errors.New("port out of range")
+
+func (c *ServerConfig) Validate() error {
+	if c.Port < 1 || c.Port > 65535 {
+		return errInvalidPort
+	}
+	return nil
+}
+
 var _ = Describe("Config", func() {
 	Context("Load from file", func() {
 		It("should load the correct value from the file", func() {
@@ -45,4 +63,268 @@ var _ = Describe("Config", func() {
 			Expect(os.Remove(fileName)).Should(Succeed())
 		})
 	})
+
+	Context("Load from memory", func() {
+		data := `{"foo": "1", "bar": {"inner_foo": "#ENV:TestKey", "inner_bar": "3"}}`
+
+		BeforeEach(func() {
+			GinkgoT().Setenv("TestKey", "2")
+		})
+
+		It("should load the config from a reader", func() {
+			var conf Config
+			Expect(config.LoadFromReader(strings.NewReader(data), "", &conf)).Should(Succeed())
+			Expect(conf.Foo).To(Equal("1"))
+			Expect(conf.Bar.InnerFoo).To(Equal("2"))
+			Expect(conf.Bar.InnerBar).To(Equal("3"))
+		})
+
+		It("should load the config from bytes", func() {
+			var conf Config
+			Expect(config.LoadFromBytes([]byte(data), "", &conf)).Should(Succeed())
+			Expect(conf.Foo).To(Equal("1"))
+			Expect(conf.Bar.InnerFoo).To(Equal("2"))
+			Expect(conf.Bar.InnerBar).To(Equal("3"))
+		})
+
+		It("should return an error for invalid JSON", func() {
+			var conf Config
+			Expect(config.LoadFromBytes([]byte("{"), "", &conf)).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Merge multiple files", func() {
+		It("should override nested fields from later files", func() {
+			dir := GinkgoT().TempDir()
+			base := filepath.Join(dir, "config.json")
+			override := filepath.Join(dir, "config.production.json")
+			Expect(os.WriteFile(base, []byte(`{"foo": "1", "bar": {"inner_foo": "#ENV:TestKey", "inner_bar": "3"}}`), 0644)).Should(Succeed())
+			Expect(os.WriteFile(override, []byte(`{"bar": {"inner_bar": "4"}}`), 0644)).Should(Succeed())
+			GinkgoT().Setenv("TestKey", "2")
+
+			var conf Config
+			Expect(config.LoadAndMerge([]string{base, override}, "", &conf)).Should(Succeed())
+			Expect(conf.Foo).To(Equal("1"))
+			Expect(conf.Bar.InnerFoo).To(Equal("2"))
+			Expect(conf.Bar.InnerBar).To(Equal("4"))
+		})
+
+		It("should return an error when a file is missing", func() {
+			var conf Config
+			Expect(config.LoadAndMerge([]string{filepath.Join(GinkgoT().TempDir(), "missing.json")}, "", &conf)).ShouldNot(Succeed())
+		})
+	})
+
+	Context("Environment override file", func() {
+		var dir, base string
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+			base = filepath.Join(dir, "config.json")
+			Expect(os.WriteFile(base, []byte(`{"foo": "1", "bar": {"inner_foo": "2", "inner_bar": "3"}}`), 0644)).Should(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "config.production.json"), []byte(`{"bar": {"inner_bar": "4"}}`), 0644)).Should(Succeed())
+		})
+
+		It("should merge the file of the environment on top of the base file", func() {
+			GinkgoT().Setenv(config.AppEnvVar, "production")
+
+			var conf Config
+			Expect(config.LoadWithEnvOverride(base, "", &conf)).Should(Succeed())
+			Expect(conf.Foo).To(Equal("1"))
+			Expect(conf.Bar.InnerFoo).To(Equal("2"))
+			Expect(conf.Bar.InnerBar).To(Equal("4"))
+		})
+
+		It("should load the base file alone when the override file is missing", func() {
+			GinkgoT().Setenv(config.AppEnvVar, "staging")
+
+			var conf Config
+			Expect(config.LoadWithEnvOverride(base, "", &conf)).Should(Succeed())
+			Expect(conf.Bar.InnerBar).To(Equal("3"))
+		})
+
+		It("should load the base file alone without an environment", func() {
+			GinkgoT().Setenv(config.AppEnvVar, "")
+
+			var conf Config
+			Expect(config.LoadWithEnvOverride(base, "", &conf)).Should(Succeed())
+			Expect(conf.Bar.InnerBar).To(Equal("3"))
+		})
+
+		It("should merge YAML files", func() {
+			base = filepath.Join(dir, "config.yaml")
+			Expect(os.WriteFile(base, []byte("foo: \"1\"\nbar:\n  inner_bar: \"3\"\n"), 0644)).Should(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "config.production.yaml"), []byte("bar:\n  inner_bar: \"4\"\n"), 0644)).Should(Succeed())
+			GinkgoT().Setenv(config.AppEnvVar, "production")
+
+			var conf Config
+			Expect(config.LoadWithEnvOverride(base, "", &conf)).Should(Succeed())
+			Expect(conf.Foo).To(Equal("1"))
+			Expect(conf.Bar.InnerBar).To(Equal("4"))
+		})
+	})
+
+	Context("Detect the format", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+			GinkgoT().Setenv("TestKey", "2")
+		})
+
+		DescribeTable("should load the file with the loader of its extension",
+			func(name, data string) {
+				path := filepath.Join(dir, name)
+				Expect(os.WriteFile(path, []byte(data), 0644)).Should(Succeed())
+
+				var conf Config
+				Expect(config.LoadAuto(path, "", &conf)).Should(Succeed())
+				Expect(conf.Foo).To(Equal("1"))
+				Expect(conf.Bar.InnerFoo).To(Equal("2"))
+			},
+			Entry("json", "config.json", `{"foo": "1", "bar": {"inner_foo": "#ENV:TestKey"}}`),
+			Entry("yaml", "config.yaml", "foo: \"1\"\nbar:\n  inner_foo: \"#ENV:TestKey\"\n"),
+			Entry("yml in upper case", "CONFIG.YML", "foo: \"1\"\nbar:\n  inner_foo: \"#ENV:TestKey\"\n"),
+			Entry("toml", "config.toml", "foo = \"1\"\n[bar]\ninner_foo = \"#ENV:TestKey\"\n"),
+		)
+
+		It("should reject unsupported extensions", func() {
+			path := filepath.Join(dir, "config.ini")
+			Expect(os.WriteFile(path, []byte("foo = 1\n"), 0644)).Should(Succeed())
+
+			var conf Config
+			err := config.LoadAuto(path, "", &conf)
+			Expect(errors.Is(err, config.ErrUnsupportedFormat)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring(".ini")))
+		})
+	})
+
+	Context("Validation", func() {
+		It("should return the error from Validate verbatim", func() {
+			var conf ServerConfig
+			Expect(config.LoadFromBytes([]byte(`{"port": 70000}`), "", &conf)).Should(Equal(errInvalidPort))
+		})
+
+		It("should succeed when Validate accepts the config", func() {
+			var conf ServerConfig
+			Expect(config.LoadFromBytes([]byte(`{"port": 8080}`), "", &conf)).Should(Succeed())
+			Expect(conf.Port).To(Equal(8080))
+		})
+	})
+
+	Context("Struct validation", func() {
+		type ContactConfig struct {
+			Email string `json:"email" validate:"required,email"`
+			URL   string `json:"url" validate:"required,url"`
+		}
+
+		It("should return the errors of every failed field", func() {
+			var conf ContactConfig
+			err := config.LoadFromBytes([]byte(`{"email": "not-an-email"}`), "", &conf,
+				config.WithStructValidator(validator.New()))
+
+			var validationErrs validator.ValidationErrors
+			Expect(errors.As(err, &validationErrs)).To(BeTrue())
+			Expect(validationErrs).To(HaveLen(2))
+			Expect(validationErrs[0].Field()).To(Equal("Email"))
+			Expect(validationErrs[0].Tag()).To(Equal("email"))
+			Expect(validationErrs[1].Field()).To(Equal("URL"))
+			Expect(validationErrs[1].Tag()).To(Equal("required"))
+		})
+
+		It("should validate the resolved values", func() {
+			GinkgoT().Setenv("TEST_CONTACT_EMAIL", "ops@example.com")
+
+			var conf ContactConfig
+			data := `{"email": "#ENV:TEST_CONTACT_EMAIL", "url": "https://example.com"}`
+			Expect(config.LoadFromBytes([]byte(data), "", &conf, config.WithStructValidator(validator.New()))).Should(Succeed())
+			Expect(conf.Email).To(Equal("ops@example.com"))
+		})
+
+		It("should not validate tags unless enabled", func() {
+			var conf ContactConfig
+			Expect(config.LoadFromBytes([]byte(`{"email": "not-an-email"}`), "", &conf)).Should(Succeed())
+		})
+	})
+
+	Context("Resolution errors", func() {
+		It("should include the field path in the error", func() {
+			data := `{"foo": "1", "bar": {"inner_foo": "#ENV:MissingTestKey"}}`
+
+			var conf Config
+			err := config.LoadFromBytes([]byte(data), "", &conf)
+			Expect(err).Should(MatchError("field Bar.InnerFoo: environment variable MissingTestKey not found"))
+		})
+	})
+
+	Context("Strict mode", func() {
+		data := `{"foo": "1", "bar": {"inner_foo": "2", "iner_bar": "3"}}`
+
+		It("should reject unknown fields", func() {
+			var conf Config
+			err := config.LoadFromBytes([]byte(data), "", &conf, config.WithStrict())
+			Expect(err).Should(MatchError(ContainSubstring(`unknown field "iner_bar"`)))
+		})
+
+		It("should ignore unknown fields by default", func() {
+			var conf Config
+			Expect(config.LoadFromBytes([]byte(data), "", &conf)).Should(Succeed())
+			Expect(conf.Bar.InnerFoo).To(Equal("2"))
+			Expect(conf.Bar.InnerBar).To(BeEmpty())
+		})
+
+		It("should accept a valid config", func() {
+			var conf Config
+			Expect(config.LoadFromBytes([]byte(`{"foo": "1"}`), "", &conf, config.WithStrict())).Should(Succeed())
+			Expect(conf.Foo).To(Equal("1"))
+		})
+	})
+
+	Context("Environment overrides", func() {
+		type OverrideConfig struct {
+			Config
+			Server struct {
+				Port    int           `json:"port"`
+				Debug   bool          `json:"debug"`
+				Timeout time.Duration `json:"timeout"`
+				APIKey  string
+			} `json:"server"`
+		}
+
+		data := `{"foo": "1", "bar": {"inner_foo": "2", "inner_bar": "3"}, "server": {"port": 80, "timeout": 1000000000}}`
+
+		It("should let env variables override nested fields", func() {
+			GinkgoT().Setenv("APP_BAR_INNER_FOO", "overridden")
+			GinkgoT().Setenv("APP_SERVER_PORT", "8080")
+			GinkgoT().Setenv("APP_SERVER_DEBUG", "true")
+			GinkgoT().Setenv("APP_SERVER_TIMEOUT", "5s")
+			GinkgoT().Setenv("APP_SERVER_API_KEY", "key")
+
+			var conf OverrideConfig
+			Expect(config.LoadFromBytes([]byte(data), "", &conf, config.WithEnvOverride("APP_"))).Should(Succeed())
+			Expect(conf.Foo).To(Equal("1"))
+			Expect(conf.Bar.InnerFoo).To(Equal("overridden"))
+			Expect(conf.Bar.InnerBar).To(Equal("3"))
+			Expect(conf.Server.Port).To(Equal(8080))
+			Expect(conf.Server.Debug).To(BeTrue())
+			Expect(conf.Server.Timeout).To(Equal(5 * time.Second))
+			Expect(conf.Server.APIKey).To(Equal("key"))
+		})
+
+		It("should not apply overrides unless enabled", func() {
+			GinkgoT().Setenv("APP_BAR_INNER_FOO", "overridden")
+
+			var conf OverrideConfig
+			Expect(config.LoadFromBytes([]byte(data), "", &conf)).Should(Succeed())
+			Expect(conf.Bar.InnerFoo).To(Equal("2"))
+		})
+
+		It("should report the field path for invalid override values", func() {
+			GinkgoT().Setenv("SVC_SERVER_PORT", "http")
+
+			var conf OverrideConfig
+			err := config.LoadFromBytes([]byte(data), "", &conf, config.WithEnvOverride("SVC_"))
+			Expect(err).Should(MatchError(ContainSubstring("field Server.Port")))
+		})
+	})
 })
@@ -0,0 +1,301 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+)
+
+// implementsTextUnmarshaler reports whether the addressable value parses itself from text
+func implementsTextUnmarshaler(v reflect.Value) bool {
+	return v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType)
+}
+
+// setFromString parses value according to the kind of the field and stores it. The encoding
+// tag of the field decides how a []byte field is decoded: "hex", "base64" or, when empty, the
+// raw bytes of value. Other slices are split on separator, with each entry trimmed, empty
+// entries dropped and the rest parsed according to the element kind.
+func setFromString(field reflect.Value, value string, tag reflect.StructTag, separator string) error {
+	if implementsTextUnmarshaler(field) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := setFromString(elem.Elem(), value, tag, separator); err != nil {
+			return err
+		}
+		field.Set(elem)
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q: %w", value, err)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.Type() == reflect.TypeFor[time.Duration]() {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid duration %q: %w", value, err)
+			}
+			field.SetInt(int64(d))
+			return nil
+		}
+		i, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q: %w", value, err)
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q: %w", value, err)
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid float %q: %w", value, err)
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := decodeBytes(value, tag.Get("encoding"))
+			if err != nil {
+				return err
+			}
+			field.SetBytes(b)
+			return nil
+		}
+		return setSliceFromString(field, value, tag, separator)
+	default:
+		return fmt.Errorf("cannot set a value of type %s from a string", field.Type())
+	}
+	return nil
+}
+
+// setSliceFromString splits value on separator and parses every non-empty entry into a new
+// element of the slice
+func setSliceFromString(field reflect.Value, value string, tag reflect.StructTag, separator string) error {
+	slice := reflect.MakeSlice(field.Type(), 0, strings.Count(value, separator)+1)
+	for _, entry := range strings.Split(value, separator) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		elem := reflect.New(field.Type().Elem()).Elem()
+		if err := setFromString(elem, entry, tag, separator); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, elem)
+	}
+	field.Set(slice)
+	return nil
+}
+
+// decodeBytes decodes value as hex or base64, or returns its raw bytes when byteEncoding is empty
+func decodeBytes(value, byteEncoding string) ([]byte, error) {
+	switch byteEncoding {
+	case "":
+		return []byte(value), nil
+	case "hex":
+		b, err := hex.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex value: %w", err)
+		}
+		return b, nil
+	case "base64":
+		b, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 value: %w", err)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q, expected hex or base64", byteEncoding)
+	}
+}
+
+// rawString is a string the config gives for a field that encoding/json can't decode it into,
+// such as a []byte tagged with an encoding or a []string. The Parser resolves it like the value of an env tag
+// and parses it with setFromString once the rest of the config is unmarshalled.
+type rawString struct {
+	value string
+	tag   reflect.StructTag
+}
+
+// rawStrings maps the path of a field, as built by the Parser, to the string given for it
+type rawStrings map[string]rawString
+
+// extractRawStrings records in raw the strings data gives for the fields of target that
+// encoding/json can't decode them into, and returns data with those values replaced by null.
+// Data that isn't a single JSON object is returned as is, for encoding/json to report.
+func extractRawStrings(data []byte, target any, raw rawStrings) []byte {
+	t := reflect.TypeOf(target)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return data
+	}
+
+	// Numbers are kept as they are written so that the rewritten data decodes the same
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var obj map[string]any
+	if err := decoder.Decode(&obj); err != nil || obj == nil {
+		return data
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return data
+	}
+
+	if !raw.extractFields(obj, t, "") {
+		return data
+	}
+	rewritten, err := json.Marshal(obj)
+	if err != nil {
+		return data
+	}
+	return rewritten
+}
+
+// extractFields extracts the raw strings of the JSON object decoded for a struct of type t found
+// at path, and reports whether it replaced any. Like encoding/json, a key matches the name of a
+// field exactly or else case-insensitively.
+func (raw rawStrings) extractFields(obj map[string]any, t reflect.Type, path string) bool {
+	extracted := false
+	for i := range t.NumField() {
+		fieldType := t.Field(i)
+		if !fieldType.IsExported() && !fieldType.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(fieldType.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		fieldPath := joinPath(path, fieldType.Name)
+
+		// The fields of embedded structs without an explicit name are promoted
+		embedded := fieldType.Type
+		if embedded.Kind() == reflect.Ptr {
+			embedded = embedded.Elem()
+		}
+		if fieldType.Anonymous && name == "" && embedded.Kind() == reflect.Struct {
+			extracted = raw.extractFields(obj, embedded, fieldPath) || extracted
+			continue
+		}
+		if !fieldType.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = fieldType.Name
+		}
+		key, ok := jsonKey(obj, name)
+		if !ok {
+			continue
+		}
+		value, ok := raw.extract(obj[key], fieldType.Type, fieldType.Tag, fieldPath)
+		obj[key] = value
+		extracted = extracted || ok
+	}
+	return extracted
+}
+
+// extract returns the value to decode in place of v, the JSON value given for a field of type t
+// found at path, and reports whether it recorded v or a value nested in it as a raw string. A
+// value replacing the one of an earlier file, as in LoadAndMerge, drops the raw strings recorded
+// for it.
+func (raw rawStrings) extract(v any, t reflect.Type, tag reflect.StructTag, path string) (any, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		delete(raw, path)
+		return v, false
+	}
+	if s, ok := v.(string); ok && decodesFromRawString(t, tag) {
+		raw[path] = rawString{value: s, tag: tag}
+		return nil, true
+	}
+	delete(raw, path)
+
+	extracted := false
+	switch t.Kind() {
+	case reflect.Struct:
+		if obj, ok := v.(map[string]any); ok {
+			extracted = raw.extractFields(obj, t, path)
+		}
+	case reflect.Map:
+		if obj, ok := v.(map[string]any); ok {
+			for key, elem := range obj {
+				elemPath := fmt.Sprintf("%s[%s]", path, key)
+				raw.dropNested(elemPath)
+				value, ok := raw.extract(elem, t.Elem(), tag, elemPath)
+				obj[key] = value
+				extracted = extracted || ok
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		raw.dropNested(path)
+		if arr, ok := v.([]any); ok {
+			for i, elem := range arr {
+				value, ok := raw.extract(elem, t.Elem(), tag, fmt.Sprintf("%s[%d]", path, i))
+				arr[i] = value
+				extracted = extracted || ok
+			}
+		}
+	}
+	return v, extracted
+}
+
+// dropNested drops the raw strings recorded for the values nested in the one at path, which is
+// replaced as a whole
+func (raw rawStrings) dropNested(path string) {
+	for key := range raw {
+		if strings.HasPrefix(key, path+".") || strings.HasPrefix(key, path+"[") {
+			delete(raw, key)
+		}
+	}
+}
+
+// decodesFromRawString reports whether a string given for a value of type t is kept for the
+// Parser: a []byte tagged with an encoding, which encoding/json would decode as base64 before
+// any reference in it is resolved, or any other slice, such as a []string given as "a,b,c" or
+// as a reference to an environment variable holding the list
+func decodesFromRawString(t reflect.Type, tag reflect.StructTag) bool {
+	if t.Kind() != reflect.Slice {
+		return false
+	}
+	return t.Elem().Kind() != reflect.Uint8 || tag.Get("encoding") != ""
+}
+
+// jsonKey returns the key of obj matching the field name, preferring an exact match
+func jsonKey(obj map[string]any, name string) (string, bool) {
+	if _, ok := obj[name]; ok {
+		return name, true
+	}
+	for key := range obj {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/catalogfi/tools/pkg/cryptutil"
+)
+
+// encryptedFileHeader starts every file written by EncryptFile, identifying the format and its
+// version. The ciphertext of the whole config follows it.
+const encryptedFileHeader = "#EncryptedCONFIG:v1\n"
+
+// ErrNotEncrypted is returned by LoadFromEncryptedFile for files that weren't written by EncryptFile
+var ErrNotEncrypted = errors.New("not an encrypted config file")
+
+// EncryptFile encrypts the config file at srcPath with the hex-encoded AES-256 hexKey and writes
+// it to dstPath, readable only by its owner. The result starts with a header identifying it as
+// an encrypted config, see IsEncryptedFile, and is loaded back with LoadFromEncryptedFile.
+func EncryptFile(srcPath, dstPath, hexKey string) error {
+	aes, err := cryptutil.NewAES256(hexKey)
+	if err != nil {
+		return fmt.Errorf("failed to create AES encryptor: %w", err)
+	}
+	file, err := readFile(srcPath)
+	if err != nil {
+		return err
+	}
+	ciphertext, err := aes.Encrypt(file)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", srcPath, err)
+	}
+	return os.WriteFile(dstPath, append([]byte(encryptedFileHeader), ciphertext...), 0600)
+}
+
+// IsEncryptedFile reports whether data is the content of a file written by EncryptFile
+func IsEncryptedFile(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptedFileHeader))
+}
+
+// LoadFromEncryptedFile decrypts the file written by EncryptFile with hexKey, then loads the
+// JSON config it contains into target like LoadFromFile, resolving references with secret.
+// Files without the header of an encrypted config fail with ErrNotEncrypted.
+func LoadFromEncryptedFile(filePath, hexKey, secret string, target any, opts ...Options) error {
+	file, err := readFile(filePath)
+	if err != nil {
+		return err
+	}
+	if !IsEncryptedFile(file) {
+		return fmt.Errorf("%s: %w", filePath, ErrNotEncrypted)
+	}
+
+	aes, err := cryptutil.NewAES256(hexKey)
+	if err != nil {
+		return fmt.Errorf("failed to create AES decryptor: %w", err)
+	}
+	data, err := aes.Decrypt(file[len(encryptedFileHeader):])
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s: %w", filePath, err)
+	}
+	return LoadFromBytes(data, secret, target, opts...)
+}
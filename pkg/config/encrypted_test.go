@@ -0,0 +1,66 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/catalogfi/tools/pkg/config"
+	"github.com/catalogfi/tools/pkg/cryptutil"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Encrypted files", func() {
+	var (
+		dir    string
+		key    string
+		plain  string
+		sealed string
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		plain = filepath.Join(dir, "config.json")
+		sealed = filepath.Join(dir, "config.json.enc")
+
+		var err error
+		key, err = cryptutil.GenerateKey()
+		Expect(err).Should(BeNil())
+
+		data := `{"foo": "1", "bar": {"inner_foo": "#ENV:TestKey", "inner_bar": "s3cret"}}`
+		Expect(os.WriteFile(plain, []byte(data), 0644)).Should(Succeed())
+		GinkgoT().Setenv("TestKey", "2")
+	})
+
+	It("should load back an encrypted config", func() {
+		Expect(config.EncryptFile(plain, sealed, key)).Should(Succeed())
+
+		data, err := os.ReadFile(sealed)
+		Expect(err).Should(BeNil())
+		Expect(config.IsEncryptedFile(data)).To(BeTrue())
+		Expect(string(data)).NotTo(ContainSubstring("s3cret"))
+
+		var conf Config
+		Expect(config.LoadFromEncryptedFile(sealed, key, "", &conf)).Should(Succeed())
+		Expect(conf.Foo).To(Equal("1"))
+		Expect(conf.Bar.InnerFoo).To(Equal("2"))
+		Expect(conf.Bar.InnerBar).To(Equal("s3cret"))
+	})
+
+	It("should reject files that aren't encrypted", func() {
+		var conf Config
+		err := config.LoadFromEncryptedFile(plain, key, "", &conf)
+		Expect(errors.Is(err, config.ErrNotEncrypted)).To(BeTrue())
+	})
+
+	It("should fail to decrypt with another key", func() {
+		Expect(config.EncryptFile(plain, sealed, key)).Should(Succeed())
+		otherKey, err := cryptutil.GenerateKey()
+		Expect(err).Should(BeNil())
+
+		var conf Config
+		Expect(config.LoadFromEncryptedFile(sealed, otherKey, "", &conf)).To(MatchError(ContainSubstring("failed to decrypt")))
+	})
+})
@@ -1,65 +1,361 @@
 package config
 
 import (
+	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
+	"log"
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/catalogfi/tools/pkg/cryptutil"
 )
 
 // Prefixes used to identify environment variable references in configuration values
 const (
-	// EnvPrefix is used for environment variables that are read directly
+	// EnvPrefix is used for environment variables that are read directly. A field can also
+	// name its environment variable with an `env:"NAME"` tag, in which case it is filled from
+	// that variable when left empty in the config. The variable may itself hold a reference
+	// such as #EncryptedENV:. []byte fields filled this way are decoded according to an
+	// `encoding:"hex"` or `encoding:"base64"` tag, while other slices such as []string are
+	// split on a comma, or the separator set with WithSliceSeparator. The loaders parse the
+	// string a config file gives for a slice field the same way, after resolving the references
+	// in it, so "hosts": "#ENV:HOSTS" fills a []string from HOSTS=a,b,c. An array keeps one
+	// element per entry, each resolved on its own.
 	EnvPrefix = "#ENV:"
 
 	// EncryptedEnvPrefix is used for environment variables that need decryption
 	EncryptedEnvPrefix = "#EncryptedENV:"
+
+	// FilePrefix is used for values read from a file, such as a mounted Kubernetes secret
+	FilePrefix = "#FILE:"
+
+	// EncryptedFilePrefix is used for values read from a file holding a hex-encoded encrypted
+	// value, such as a mounted secret encrypted like the values of #EncryptedENV:
+	EncryptedFilePrefix = "#EncryptedFILE:"
 )
 
-// Parser is responsible for resolving environment variables in configuration data
+// ErrRecursionLimit is returned when the config is nested deeper than the parser's maximum depth
+var ErrRecursionLimit = errors.New("maximum recursion depth exceeded")
+
+// ErrNotFound is wrapped by resolution errors caused by a missing value, such as an unset
+// environment variable or a missing secret, which the MissingEnvPolicy applies to
+var ErrNotFound = errors.New("not found")
+
+// ErrIndirectionLimit is returned when a reference resolves to another reference more times
+// than maxIndirection, which usually means two references point at each other
+var ErrIndirectionLimit = errors.New("too many levels of indirection")
+
+// maxIndirection is the number of chained references followed for a single value, e.g.
+// #ENV:A where A="#FILE:/path" takes two
+const maxIndirection = 4
+
+// ErrMissingRequired is returned when fields tagged with `required:"true"` are still empty
+// after resolution. The wrapping error lists the path of every offending field.
+var ErrMissingRequired = errors.New("missing required fields")
+
+// ErrMissingAESSecret is returned when a config holds an encrypted value but the parser has
+// neither an AES secret nor a Decryptor to decrypt it with
+var ErrMissingAESSecret = errors.New("requires an AES secret but none was configured")
+
+// SecretResolver resolves a reference to a value held in an external secret store. The key
+// is the part of the configuration value that follows the prefix the resolver is registered for.
+type SecretResolver interface {
+	Resolve(ctx context.Context, key string) (string, error)
+}
+
+// ResolverFunc adapts a plain function to a SecretResolver for resolvers that do no I/O
+type ResolverFunc func(key string) (string, error)
+
+// Resolve calls f(key)
+func (f ResolverFunc) Resolve(_ context.Context, key string) (string, error) {
+	return f(key)
+}
+
+// Parser is responsible for resolving environment variables in configuration data.
+//
+// A Parser is safe for concurrent use: ProcessStruct may run on several goroutines at once, on
+// distinct targets, and RegisterResolver may be called at any time. AESSecret and Decryptor
+// must not be changed while the parser is in use, and custom resolvers and decryptors must be
+// safe for concurrent use themselves, as the built-in ones are.
 type Parser struct {
 	// AESSecret is the secret key used for decrypting encrypted environment variables
 	AESSecret string
+
+	// Decryptor decrypts encrypted environment variables, such as a KMS-backed implementation.
+	// When nil, an AES256 decryptor is built from AESSecret instead.
+	Decryptor cryptutil.DataDecryptor
+
+	// initOnce sets up the resolvers and options of a Parser not created through NewParser
+	initOnce sync.Once
+
+	// mu guards resolvers and the cached AES decryptor
+	mu sync.RWMutex
+
+	// resolvers maps a value prefix to the resolver responsible for it
+	resolvers map[string]SecretResolver
+
+	// aes is the decryptor built from aesKey, the value of AESSecret it was last built from
+	aes    *cryptutil.AES256
+	aesKey string
+
+	opts *options
 }
 
 // NewParser creates a new environment variable parser with the given AES secret key
-func NewParser(aesSecret string) *Parser {
-	return &Parser{
+func NewParser(aesSecret string, opts ...Options) *Parser {
+	p := &Parser{
 		AESSecret: aesSecret,
+		opts:      applyOptions(opts),
 	}
+	p.registerBuiltins()
+	return p
+}
+
+// NewParserWithDecryptor creates a new environment variable parser that decrypts encrypted
+// environment variables with the given decryptor
+func NewParserWithDecryptor(decryptor cryptutil.DataDecryptor, opts ...Options) *Parser {
+	p := NewParser("", opts...)
+	p.Decryptor = decryptor
+	return p
+}
+
+// registerBuiltins registers the resolvers for the prefixes supported out of the box
+func (p *Parser) registerBuiltins() {
+	p.resolvers = map[string]SecretResolver{
+		EnvPrefix:           ResolverFunc(GetEnvValue),
+		EncryptedEnvPrefix:  ResolverFunc(p.resolveEncryptedEnv),
+		FilePrefix:          ResolverFunc(GetFileValue),
+		EncryptedFilePrefix: ResolverFunc(p.resolveEncryptedFile),
+		VaultPrefix:         &VaultResolver{},
+		AWSPrefix:           &AWSSecretResolver{},
+	}
+}
+
+// init sets up the resolvers and options that are still missing, for a Parser that was not
+// created through NewParser
+func (p *Parser) init() {
+	p.initOnce.Do(func() {
+		if p.resolvers == nil {
+			p.registerBuiltins()
+		}
+		if p.opts == nil {
+			p.opts = defaultOptions()
+		}
+	})
+}
+
+// RegisterResolver makes the parser resolve values starting with prefix through the given
+// resolver, replacing any resolver previously registered for the same prefix
+func (p *Parser) RegisterResolver(prefix string, resolver SecretResolver) {
+	p.init()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resolvers[prefix] = resolver
+}
+
+// RegisterPrefix makes the parser resolve values starting with prefix by calling resolver
+// with the rest of the value, e.g. registering "#MYVAULT:" resolves "#MYVAULT:db" by calling
+// resolver("db"). Registering a built-in prefix replaces the built-in behavior.
+func (p *Parser) RegisterPrefix(prefix string, resolver func(key string) (string, error)) {
+	p.RegisterResolver(prefix, ResolverFunc(resolver))
+}
+
+// options returns the parser options, falling back to the defaults for a Parser that
+// was not created through NewParser
+func (p *Parser) options() *options {
+	p.init()
+	return p.opts
 }
 
 // ProcessStruct processes all string fields in a struct, replacing environment variable
 // references with their values
 func (p *Parser) ProcessStruct(structPtr any) error {
+	return p.ProcessStructContext(context.Background(), structPtr)
+}
+
+// ProcessStructContext is like ProcessStruct, passing ctx to the resolvers so that a deadline
+// or cancellation aborts slow fetches from remote secret stores
+func (p *Parser) ProcessStructContext(ctx context.Context, structPtr any) error {
+	return p.processStruct(ctx, structPtr, nil)
+}
+
+// processStruct is ProcessStructContext, additionally parsing the raw strings the loaders set
+// aside for fields that encoding/json couldn't decode them into
+func (p *Parser) processStruct(ctx context.Context, structPtr any, raw rawStrings) error {
 	val := reflect.ValueOf(structPtr)
 	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
 		return fmt.Errorf("expected pointer to struct, got %T", structPtr)
 	}
 
-	return p.processStructFields(val.Elem())
+	state := newWalkState(ctx)
+	state.raw = raw
+	if err := p.processStructFields(state, val.Elem(), "", 0); err != nil {
+		return err
+	}
+	if secrets := p.options().secrets; secrets != nil {
+		secrets.replace(state.secrets)
+	}
+
+	if prefix := p.options().envOverridePrefix; prefix != "" {
+		if err := p.applyEnvOverrides(newWalkState(ctx), val.Elem(), "", prefix); err != nil {
+			return err
+		}
+	}
+
+	var missing []string
+	collectMissingRequired(newWalkState(ctx), val.Elem(), "", &missing)
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingRequired, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// walkState tracks the pointers visited during a single traversal so that cyclic
+// structures are only processed once, along with the context of the traversal, the raw
+// strings to parse and the paths of the fields resolved from a secret prefix
+type walkState struct {
+	ctx     context.Context
+	visited map[visitKey]struct{}
+	raw     rawStrings
+	secrets map[string]struct{}
+}
+
+// visitKey identifies a pointer by address and type, since a struct and its first
+// field share the same address
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+func newWalkState(ctx context.Context) *walkState {
+	return &walkState{ctx: ctx, visited: make(map[visitKey]struct{}), secrets: make(map[string]struct{})}
+}
+
+// visit records the pointer and reports whether it was seen for the first time
+func (s *walkState) visit(ptr reflect.Value) bool {
+	key := visitKey{ptr: ptr.Pointer(), typ: ptr.Type()}
+	if _, ok := s.visited[key]; ok {
+		return false
+	}
+	s.visited[key] = struct{}{}
+	return true
+}
+
+// checkDepth returns ErrRecursionLimit once the traversal is nested too deeply
+func (p *Parser) checkDepth(path string, depth int) error {
+	if maxDepth := p.options().maxDepth; depth > maxDepth {
+		return fieldError(path, fmt.Errorf("%w (%d)", ErrRecursionLimit, maxDepth))
+	}
+	return nil
+}
+
+// fieldError prefixes err with the path of the field that caused it
+func fieldError(path string, err error) error {
+	if path == "" {
+		return err
+	}
+	return fmt.Errorf("field %s: %w", path, err)
+}
+
+// joinPath appends a struct field name to the path of its parent
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
 }
 
 // processStructFields processes all fields in a struct, handling environment variables in string fields
-func (p *Parser) processStructFields(structVal reflect.Value) error {
+func (p *Parser) processStructFields(state *walkState, structVal reflect.Value, path string, depth int) error {
+	if err := p.checkDepth(path, depth); err != nil {
+		return err
+	}
+
+	structType := structVal.Type()
 	for i := 0; i < structVal.NumField(); i++ {
 		field := structVal.Field(i)
 
-		if !field.CanInterface() {
-			continue // Skip unexported fields
+		// Skip unexported fields, but descend into embedded structs of unexported types
+		// since their exported fields are promoted and settable
+		if !field.CanInterface() && !structType.Field(i).Anonymous {
+			continue
 		}
 
-		if err := p.processField(field); err != nil {
+		fieldPath := joinPath(path, structType.Field(i).Name)
+		if err := p.processField(state, field, fieldPath, depth); err != nil {
 			return err
 		}
+
+		// Fill empty fields from the environment variable named by the env tag
+		if envKey := structType.Field(i).Tag.Get("env"); envKey != "" && field.CanSet() && isEmptyValue(field) {
+			if err := p.fillFromEnv(state, field, structType.Field(i).Tag, fieldPath, envKey); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fillFromEnv sets the field from the environment variable envKey. The variable may hold a
+// reference such as #EncryptedENV:, which is resolved first. []byte fields are decoded
+// according to their encoding tag and other slices are split on the slice separator.
+func (p *Parser) fillFromEnv(state *walkState, field reflect.Value, tag reflect.StructTag, path, envKey string) error {
+	value := os.Getenv(envKey)
+	if value == "" {
+		return nil
+	}
+	value, secret, err := p.followReferences(state.ctx, value)
+	if err != nil {
+		if p.ignoreMissing(path, err) {
+			return nil
+		}
+		return fieldError(path, err)
+	}
+	if err := setFromString(field, value, tag, p.options().sliceSeparator); err != nil {
+		return fieldError(path, err)
+	}
+	if secret {
+		state.secrets[path] = struct{}{}
+	}
+	return nil
+}
+
+// setFromRaw resolves the references in a raw string set aside by the loaders, like the value of
+// an env tag, and parses it into the field
+func (p *Parser) setFromRaw(state *walkState, field reflect.Value, path string, raw rawString) error {
+	value, secret, err := p.processEnvString(state.ctx, raw.value)
+	if err != nil {
+		if p.ignoreMissing(path, err) {
+			return nil
+		}
+		return fieldError(path, err)
+	}
+	if err := setFromString(field, value, raw.tag, p.options().sliceSeparator); err != nil {
+		return fieldError(path, err)
+	}
+	if secret {
+		state.secrets[path] = struct{}{}
 	}
 	return nil
 }
 
 // processField handles a single field, checking its type and processing accordingly
-func (p *Parser) processField(field reflect.Value) error {
+func (p *Parser) processField(state *walkState, field reflect.Value, path string, depth int) error {
+	if raw, ok := state.raw[path]; ok && field.CanSet() {
+		return p.setFromRaw(state, field, path, raw)
+	}
+
+	if field.Kind() == reflect.Struct {
+		// Process nested struct, which may itself be unsettable when embedded from an
+		// unexported type while its exported fields are still settable
+		return p.processStructFields(state, field, path, depth+1)
+	}
+
 	if !field.CanSet() {
 		return nil // Skip if field can't be set
 	}
@@ -70,113 +366,382 @@ func (p *Parser) processField(field reflect.Value) error {
 			return nil
 		}
 		// Process string field for environment variables
-		newVal, err := p.processEnvString(field.String())
+		newVal, secret, err := p.processEnvString(state.ctx, field.String())
 		if err != nil {
-			return err
+			if !p.ignoreMissing(path, err) {
+				return fieldError(path, err)
+			}
+			newVal = ""
+		}
+		if secret {
+			state.secrets[path] = struct{}{}
 		}
 		if newVal != field.String() {
 			field.SetString(newVal)
 		}
-	case reflect.Struct:
-		// Process nested struct
-		return p.processStructFields(field)
 	case reflect.Ptr:
-		// Handle pointers to structs
-		if !field.IsNil() && field.Elem().Kind() == reflect.Struct {
-			return p.processStructFields(field.Elem())
+		// Handle pointers to structs and to other values such as a *string, skipping nil
+		// pointers and the ones already processed in a cycle or through another pointer
+		if field.IsNil() || !state.visit(field) {
+			return nil
+		}
+		if field.Elem().Kind() == reflect.Struct {
+			return p.processStructFields(state, field.Elem(), path, depth+1)
+		}
+		return p.processField(state, field.Elem(), path, depth+1)
+	case reflect.Interface:
+		// Handle interface values such as the ones in a map[string]any decoded from JSON by
+		// processing a settable copy of the dynamic value
+		if field.IsNil() {
+			return nil
 		}
+		elem := field.Elem()
+		tmpValue := reflect.New(elem.Type()).Elem()
+		tmpValue.Set(elem)
+		if err := p.processField(state, tmpValue, path, depth); err != nil {
+			return err
+		}
+		field.Set(tmpValue)
 	case reflect.Map:
 		// Process map values
-		return p.processMap(field)
+		return p.processMap(state, field, path, depth+1)
 	case reflect.Slice:
 		// Process slice elements
-		return p.processSlice(field)
+		return p.processSlice(state, field, path, depth+1)
 	}
 
 	return nil
 }
 
 // processMap processes all entries in a map
-func (p *Parser) processMap(mapField reflect.Value) error {
+func (p *Parser) processMap(state *walkState, mapField reflect.Value, path string, depth int) error {
+	if err := p.checkDepth(path, depth); err != nil {
+		return err
+	}
+
 	for _, key := range mapField.MapKeys() {
 		mapValue := mapField.MapIndex(key)
 
-		// For maps, we need to create a new value, process it, and set it back
-		switch mapValue.Kind() {
-		case reflect.Struct, reflect.Ptr:
-			// Make a copy of the value
-			tmpValue := reflect.New(mapValue.Type()).Elem()
-			tmpValue.Set(mapValue)
-
-			// Process the copy
-			if err := p.processField(tmpValue); err != nil {
-				return err
-			}
+		// Map values aren't addressable, so we need to create a new value, process it, and
+		// set it back. This covers values of any type, including nested maps and slices.
+		tmpValue := reflect.New(mapValue.Type()).Elem()
+		tmpValue.Set(mapValue)
 
-			// Set the processed value back into the map
-			mapField.SetMapIndex(key, tmpValue)
-		case reflect.String:
-			// Process string values in the map
-			strVal := mapValue.String()
-			newVal, err := p.processEnvString(strVal)
-			if err != nil {
-				return err
-			}
-			if newVal != strVal {
-				mapField.SetMapIndex(key, reflect.ValueOf(newVal))
-			}
+		// Process the copy
+		if err := p.processField(state, tmpValue, fmt.Sprintf("%s[%v]", path, key.Interface()), depth); err != nil {
+			return err
 		}
+
+		// Set the processed value back into the map
+		mapField.SetMapIndex(key, tmpValue)
 	}
 	return nil
 }
 
 // processSlice processes all elements in a slice
-func (p *Parser) processSlice(sliceField reflect.Value) error {
+func (p *Parser) processSlice(state *walkState, sliceField reflect.Value, path string, depth int) error {
+	if err := p.checkDepth(path, depth); err != nil {
+		return err
+	}
+
 	for i := range sliceField.Len() {
 		elem := sliceField.Index(i)
-		if err := p.processField(elem); err != nil {
+		if err := p.processField(state, elem, fmt.Sprintf("%s[%d]", path, i), depth); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// processEnvString processes environment variables in a string field
-func (p *Parser) processEnvString(value string) (string, error) {
-	// Check for environment variable prefix
-	if strings.HasPrefix(value, EnvPrefix) {
-		envKey := strings.TrimPrefix(value, EnvPrefix)
-		return GetEnvValue(envKey)
-	} else if strings.HasPrefix(value, EncryptedEnvPrefix) {
-		// Handle encrypted environment variables
-		envKey := strings.TrimPrefix(value, EncryptedEnvPrefix)
-		envValue, err := GetEnvValue(envKey)
+// ignoreMissing reports whether the resolution error is a missing value that the
+// MissingEnvPolicy allows to be left empty, logging it when the policy asks to
+func (p *Parser) ignoreMissing(path string, err error) bool {
+	if !isMissing(err) {
+		return false
+	}
+
+	switch p.options().missingEnvPolicy {
+	case MissingEnvEmpty:
+		return true
+	case MissingEnvWarn:
+		log.Printf("config: %v, leaving it empty", fieldError(path, err))
+		return true
+	default:
+		return false
+	}
+}
+
+// isMissing reports whether err means the referenced value doesn't exist
+func isMissing(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, fs.ErrNotExist)
+}
+
+// processEnvString processes environment variables in a string field. The boolean reports
+// whether the value was resolved from a secret prefix, or embeds a secret inline.
+func (p *Parser) processEnvString(ctx context.Context, value string) (string, bool, error) {
+	// Expand inline ${VAR} and ${#ENV:VAR} references when the value doesn't start with a
+	// registered prefix such as #ENV:
+	if _, _, ok := p.resolverFor(value); !ok {
+		// A value embedding a secret, such as a DSN with an inline password, is a secret too
+		containsSecret := false
+		expanded, err := expand(value, func(name string) (string, error) {
+			if _, _, ok := p.resolverFor(name); ok {
+				resolved, secret, err := p.followReferences(ctx, name)
+				containsSecret = containsSecret || secret
+				return resolved, err
+			}
+			return GetEnvValue(name)
+		})
+		return expanded, containsSecret, err
+	}
+	return p.followReferences(ctx, value)
+}
+
+// followReferences resolves value when it is a reference and keeps resolving the result while
+// it is itself a reference. Values that aren't references are returned unchanged. The boolean
+// reports whether one of the references was to a secret, see Secrets.
+func (p *Parser) followReferences(ctx context.Context, value string) (string, bool, error) {
+	// Follow the reference, and the references it resolves to, up to maxIndirection times.
+	// Resolved values are never expanded since secrets may legitimately contain a $.
+	secret := false
+	for range maxIndirection {
+		prefix, resolver, ok := p.resolverFor(value)
+		if !ok {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return "", false, err
+		}
+		resolved, err := resolver.Resolve(ctx, strings.TrimPrefix(value, prefix))
 		if err != nil {
-			return "", err
+			return "", false, err
+		}
+		secret = secret || isSecretPrefix(prefix)
+		value = resolved
+	}
+
+	if _, _, ok := p.resolverFor(value); ok {
+		return "", false, fmt.Errorf("%w (%d)", ErrIndirectionLimit, maxIndirection)
+	}
+	return value, secret, nil
+}
+
+// ExpandEnv replaces ${VAR} references anywhere in the value with the environment variable's
+// value. ${VAR:-default} falls back to default when the variable is unset or empty, and $$ is an
+// escaped literal $. Any other $ is kept as is, so values such as password hashes are untouched.
+//
+// The parser additionally accepts any registered prefix inside the braces, so a single value
+// can combine several references, e.g. "${#ENV:DB_HOST}:${#ENV:DB_PORT}" or
+// "postgres://app:${#EncryptedENV:DB_PASS}@db/app".
+func ExpandEnv(value string) (string, error) {
+	return expand(value, GetEnvValue)
+}
+
+// expand replaces the ${...} references in value with the result of lookup
+func expand(value string, lookup func(name string) (string, error)) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+
+		switch value[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(value[i+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated ${ in %q", value)
+			}
+			expr := value[i+2 : i+2+end]
+			name, fallback, hasFallback := strings.Cut(expr, ":-")
+			if name == "" {
+				return "", fmt.Errorf("empty variable name in %q", value)
+			}
+
+			envValue, err := lookup(name)
+			if err != nil {
+				if !hasFallback || !isMissing(err) {
+					return "", err
+				}
+				envValue = fallback
+			}
+			b.WriteString(envValue)
+			i += end + 2
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), nil
+}
+
+// resolverFor returns the registered resolver whose prefix matches the value. When several
+// prefixes match, the longest one wins.
+func (p *Parser) resolverFor(value string) (string, SecretResolver, bool) {
+	p.init()
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var (
+		matched  string
+		resolver SecretResolver
+	)
+	for prefix, r := range p.resolvers {
+		if strings.HasPrefix(value, prefix) && len(prefix) > len(matched) {
+			matched, resolver = prefix, r
 		}
+	}
+	return matched, resolver, resolver != nil
+}
 
-		return p.decryptEnvValue(envValue)
+// resolveEncryptedEnv reads an environment variable and decrypts its value
+func (p *Parser) resolveEncryptedEnv(envKey string) (string, error) {
+	// Check for a key first, failing on an empty one deep in cryptutil is much less helpful
+	if p.Decryptor == nil && p.AESSecret == "" {
+		return "", ErrMissingAESSecret
+	}
+	envValue, err := GetEnvValue(envKey)
+	if err != nil {
+		return "", err
 	}
+	return p.decryptEnvValue(envValue)
+}
 
-	// Return original value if no environment variable prefix is found
+// resolveEncryptedFile reads a file and decrypts its content. A missing file is reported as
+// such, and subject to the MissingEnvPolicy, while a file that can't be decrypted fails with
+// an error naming the file.
+func (p *Parser) resolveEncryptedFile(filePath string) (string, error) {
+	if p.Decryptor == nil && p.AESSecret == "" {
+		return "", ErrMissingAESSecret
+	}
+	fileValue, err := GetFileValue(filePath)
+	if err != nil {
+		return "", err
+	}
+	value, err := p.decryptEnvValue(fileValue)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt file %s: %w", filePath, err)
+	}
 	return value, nil
 }
 
 // decryptEnvValue decrypts an encrypted environment variable value
 func (p *Parser) decryptEnvValue(encryptedValue string) (string, error) {
+	if p.Decryptor != nil {
+		data, err := hex.DecodeString(encryptedValue)
+		if err != nil {
+			return "", fmt.Errorf("invalid hex encrypted value: %w", err)
+		}
+		plaintext, err := p.Decryptor.Decrypt(data)
+		if err != nil {
+			return "", err
+		}
+		return string(plaintext), nil
+	}
+
+	aesDecryptor, err := p.aesDecryptor()
+	if err != nil {
+		return "", err
+	}
+	return aesDecryptor.DecryptHexToString(encryptedValue)
+}
+
+// aesDecryptor returns the AES256 decryptor for AESSecret, building it on first use and again
+// whenever AESSecret changes between uses
+func (p *Parser) aesDecryptor() (*cryptutil.AES256, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.aes != nil && p.aesKey == p.AESSecret {
+		return p.aes, nil
+	}
+
 	aesDecryptor, err := cryptutil.NewAES256(p.AESSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES decryptor: %w", err)
+	}
+	p.aes, p.aesKey = aesDecryptor, p.AESSecret
+	return aesDecryptor, nil
+}
+
+// DecryptValue decrypts a hex-encoded value encrypted with the hex-encoded AES-256 aesSecret,
+// such as the value of an environment variable referenced with #EncryptedENV:
+func DecryptValue(hexValue, aesSecret string) (string, error) {
+	aesDecryptor, err := cryptutil.NewAES256(aesSecret)
 	if err != nil {
 		return "", fmt.Errorf("failed to create AES decryptor: %w", err)
 	}
 
-	return aesDecryptor.DecryptHexToString(encryptedValue)
+	return aesDecryptor.DecryptHexToString(hexValue)
+}
+
+// collectMissingRequired walks the struct and appends the path of every empty field tagged
+// with `required:"true"`, descending into nested structs and non-nil struct pointers
+func collectMissingRequired(state *walkState, structVal reflect.Value, prefix string, missing *[]string) {
+	structType := structVal.Type()
+	for i := 0; i < structVal.NumField(); i++ {
+		fieldType := structType.Field(i)
+		if !fieldType.IsExported() && !fieldType.Anonymous {
+			continue
+		}
+
+		field := structVal.Field(i)
+		path := joinPath(prefix, fieldType.Name)
+
+		if fieldType.Tag.Get("required") == "true" && isEmptyValue(field) {
+			*missing = append(*missing, path)
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.Struct:
+			collectMissingRequired(state, field, path, missing)
+		case reflect.Ptr:
+			if !field.IsNil() && field.Elem().Kind() == reflect.Struct && state.visit(field) {
+				collectMissingRequired(state, field.Elem(), path, missing)
+			}
+		}
+	}
+}
+
+// isEmptyValue reports whether the value should be treated as not provided
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
 }
 
 // GetEnvValue retrieves an environment variable value
 func GetEnvValue(envKey string) (string, error) {
 	envValue := os.Getenv(envKey)
 	if envValue == "" {
-		return "", fmt.Errorf("environment variable %s not found", envKey)
+		return "", fmt.Errorf("environment variable %s %w", envKey, ErrNotFound)
 	}
 	return envValue, nil
 }
+
+// GetEnvValueOrDefault retrieves an environment variable value, returning fallback when the
+// variable is unset or empty
+func GetEnvValueOrDefault(envKey, fallback string) string {
+	if envValue := os.Getenv(envKey); envValue != "" {
+		return envValue
+	}
+	return fallback
+}
+
+// GetFileValue reads a file and returns its contents with surrounding whitespace trimmed
+func GetFileValue(filePath string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+)
+
+// stripJSONC turns a JSON document with comments and trailing commas into plain JSON. Line
+// and block comments and trailing commas before a closing } or ] are replaced by spaces rather
+// than removed, and newlines inside block comments are kept, so the offsets and line numbers
+// reported by the JSON decoder still point into the original document.
+func stripJSONC(data []byte) ([]byte, error) {
+	out := make([]byte, len(data))
+	copy(out, data)
+
+	inString := false
+	lastComma := -1
+	for i := 0; i < len(out); i++ {
+		c := out[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++ // Skip the escaped character, which may be a quote
+			case '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			lastComma = -1
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := bytes.Index(out[i+2:], []byte("*/"))
+			if end < 0 {
+				return nil, errors.New("unterminated block comment")
+			}
+			end += i + 4
+			for ; i < end; i++ {
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+			}
+			i--
+		case c == ',':
+			lastComma = i
+		case c == '}' || c == ']':
+			if lastComma >= 0 {
+				out[lastComma] = ' '
+			}
+			lastComma = -1
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			lastComma = -1
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,53 @@
+package config_test
+
+import (
+	"github.com/catalogfi/tools/pkg/config"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Comments", func() {
+	BeforeEach(func() {
+		GinkgoT().Setenv("TestKey", "2")
+	})
+
+	It("should load a config with comments and trailing commas", func() {
+		data := `{
+  // The name of the service
+  "foo": "1", /* inline */
+  "bar": {
+    "inner_foo": "#ENV:TestKey", // resolved from the environment
+    /* a block comment
+       over several lines */
+    "inner_bar": "http://example.com/*not-a-comment*/",
+  },
+}`
+		var conf Config
+		Expect(config.LoadFromBytes([]byte(data), "", &conf, config.WithComments())).Should(Succeed())
+		Expect(conf.Foo).To(Equal("1"))
+		Expect(conf.Bar.InnerFoo).To(Equal("2"))
+		Expect(conf.Bar.InnerBar).To(Equal("http://example.com/*not-a-comment*/"))
+	})
+
+	It("should keep loading plain JSON", func() {
+		data := `{"foo": "1", "bar": {"inner_foo": "#ENV:TestKey", "inner_bar": "a \"quoted\" // value,"}}`
+
+		var conf Config
+		Expect(config.LoadFromBytes([]byte(data), "", &conf, config.WithComments())).Should(Succeed())
+		Expect(conf.Foo).To(Equal("1"))
+		Expect(conf.Bar.InnerFoo).To(Equal("2"))
+		Expect(conf.Bar.InnerBar).To(Equal(`a "quoted" // value,`))
+	})
+
+	It("should reject comments unless enabled", func() {
+		var conf Config
+		Expect(config.LoadFromBytes([]byte("{\n// comment\n\"foo\": \"1\"}"), "", &conf)).ShouldNot(Succeed())
+	})
+
+	It("should reject an unterminated block comment", func() {
+		var conf Config
+		err := config.LoadFromBytes([]byte(`{"foo": "1"} /* comment`), "", &conf, config.WithComments())
+		Expect(err).To(MatchError("unterminated block comment"))
+	})
+})
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// RedactedValue replaces the value of secret fields in masked output
+const RedactedValue = "***REDACTED***"
+
+// RedactPlaceholder replaces the value of secret fields in the output of Redact
+const RedactPlaceholder = "***"
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// WriteMasked writes cfg to w as indented JSON, replacing by RedactedValue the value of every
+// field tagged with `secret:"true"`, in nested structs, maps and slices as well. With
+// WithSecrets, the fields of cfg that were resolved from a secret prefix such as #EncryptedENV:
+// are replaced too.
+func WriteMasked(w io.Writer, cfg any, opts ...Options) error {
+	m := masker{placeholder: RedactedValue, secrets: applyOptions(opts).secrets}
+	masked, err := m.mask(reflect.ValueOf(cfg), "")
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(masked)
+}
+
+// DumpResolved writes cfg to w as indented JSON, showing the values a loaded config actually
+// holds after references are resolved and secrets decrypted, to debug settings that don't take
+// effect. Secrets are written in clear unless WithMaskedSecrets is given, so the output must
+// be handled with care.
+func DumpResolved(cfg any, w io.Writer, opts ...Options) error {
+	if applyOptions(opts).maskSecrets {
+		return WriteMasked(w, cfg, opts...)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cfg)
+}
+
+// Redact returns cfg as compact JSON for logging, hiding the same secrets as WriteMasked behind
+// RedactPlaceholder, including the resolved fields recorded in the Secrets given with
+// WithSecrets. Strings that still hold a reference to a secret, such as #EncryptedENV:KEY in a
+// config that wasn't resolved, are replaced too.
+func Redact(cfg any, opts ...Options) (string, error) {
+	m := masker{placeholder: RedactPlaceholder, prefixes: secretPrefixes, secrets: applyOptions(opts).secrets}
+	redacted, err := m.mask(reflect.ValueOf(cfg), "")
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(redacted)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// masker replaces secret values with a placeholder
+type masker struct {
+	placeholder string
+
+	// prefixes are the prefixes of string values that are replaced as well
+	prefixes []string
+
+	// secrets are the fields resolved from a secret prefix, which are replaced as well
+	secrets *Secrets
+}
+
+// mask converts v, found at path in the config, into a value that marshals like v would with
+// encoding/json, except that fields tagged as secret, recorded secrets and strings starting
+// with one of the prefixes are replaced by the placeholder. Paths are built like the ones the
+// Parser records in Secrets.
+func (m masker) mask(v reflect.Value, path string) (any, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if m.secrets.contains(path) {
+		return m.placeholder, nil
+	}
+
+	// Types with their own encoding are opaque, marshal them as they are
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil, nil
+		}
+		return v.Interface(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return m.mask(v.Elem(), path)
+	case reflect.Struct:
+		var obj orderedObject
+		if err := obj.addFields(m, v, path, 0); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		obj := make(map[string]any, v.Len())
+		for _, key := range v.MapKeys() {
+			value, err := m.mask(v.MapIndex(key), fmt.Sprintf("%s[%v]", path, key.Interface()))
+			if err != nil {
+				return nil, err
+			}
+			obj[fmt.Sprint(key.Interface())] = value
+		}
+		return obj, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface(), nil // Byte slices are encoded as base64
+		}
+		arr := make([]any, v.Len())
+		for i := range v.Len() {
+			value, err := m.mask(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = value
+		}
+		return arr, nil
+	case reflect.Func, reflect.Chan, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return nil, fmt.Errorf("unsupported type %s", v.Type())
+	case reflect.String:
+		for _, prefix := range m.prefixes {
+			if strings.HasPrefix(v.String(), prefix) {
+				return m.placeholder, nil
+			}
+		}
+		return v.Interface(), nil
+	default:
+		return v.Interface(), nil
+	}
+}
+
+// objectField is a single field of an orderedObject
+type objectField struct {
+	name  string
+	value any
+	depth int
+}
+
+// orderedObject is a JSON object that keeps the declaration order of struct fields
+type orderedObject []objectField
+
+// addFields appends the fields of the struct found at path, following the encoding/json rules
+// for field names, omitempty and embedded structs
+func (o *orderedObject) addFields(m masker, v reflect.Value, path string, depth int) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		fieldType := t.Field(i)
+		if !fieldType.IsExported() && !fieldType.Anonymous {
+			continue
+		}
+
+		tag := fieldType.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		field := v.Field(i)
+		fieldPath := joinPath(path, fieldType.Name)
+
+		// Promote the fields of embedded structs without an explicit name
+		if fieldType.Anonymous && name == "" {
+			embedded := field
+			if embedded.Kind() == reflect.Ptr {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := o.addFields(m, embedded, fieldPath, depth+1); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !fieldType.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = fieldType.Name
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyValue(field) {
+			continue
+		}
+
+		var value any = m.placeholder
+		if fieldType.Tag.Get("secret") != "true" {
+			var err error
+			if value, err = m.mask(field, fieldPath); err != nil {
+				return fmt.Errorf("field %s: %w", fieldType.Name, err)
+			}
+		}
+		o.add(objectField{name: name, value: value, depth: depth})
+	}
+	return nil
+}
+
+// add appends the field, keeping the least nested one when an embedded struct promotes a
+// field with the same name
+func (o *orderedObject) add(field objectField) {
+	for i, existing := range *o {
+		if existing.name == field.name {
+			if field.depth < existing.depth {
+				(*o)[i] = field
+			}
+			return
+		}
+	}
+	*o = append(*o, field)
+}
+
+// MarshalJSON encodes the object with its fields in order
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(field.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
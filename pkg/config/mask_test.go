@@ -0,0 +1,184 @@
+package config_test
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/catalogfi/tools/pkg/config"
+	"github.com/catalogfi/tools/pkg/cryptutil"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password" secret:"true"`
+}
+
+type RedactedConfig struct {
+	Name    string `json:"name"`
+	Regions map[string][]struct {
+		Endpoint string      `json:"endpoint"`
+		Auth     Credentials `json:"auth"`
+	} `json:"regions"`
+	Token string `json:"token"`
+}
+
+type MaskedConfig struct {
+	Name     string                 `json:"name"`
+	APIKey   string                 `json:"api_key" secret:"true"`
+	Database Credentials            `json:"database"`
+	Replicas []Credentials          `json:"replicas"`
+	Services map[string]Credentials `json:"services"`
+	Internal string                 `json:"-"`
+	Optional string                 `json:"optional,omitempty"`
+}
+
+var _ = Describe("Masking", func() {
+	It("should mask secret fields while keeping ordinary fields", func() {
+		conf := MaskedConfig{
+			Name:     "service",
+			APIKey:   "api-s3cret",
+			Database: Credentials{Username: "admin", Password: "db-s3cret"},
+			Replicas: []Credentials{{Username: "replica", Password: "replica-s3cret"}},
+			Services: map[string]Credentials{"cache": {Username: "cache", Password: "cache-s3cret"}},
+			Internal: "internal",
+		}
+
+		var buf bytes.Buffer
+		Expect(config.WriteMasked(&buf, &conf)).Should(Succeed())
+		Expect(buf.String()).NotTo(ContainSubstring("s3cret"))
+		Expect(buf.String()).NotTo(ContainSubstring("internal"))
+		Expect(buf.String()).NotTo(ContainSubstring("optional"))
+
+		var masked map[string]any
+		Expect(json.Unmarshal(buf.Bytes(), &masked)).Should(Succeed())
+		Expect(masked).To(Equal(map[string]any{
+			"name":     "service",
+			"api_key":  config.RedactedValue,
+			"database": map[string]any{"username": "admin", "password": config.RedactedValue},
+			"replicas": []any{map[string]any{"username": "replica", "password": config.RedactedValue}},
+			"services": map[string]any{"cache": map[string]any{"username": "cache", "password": config.RedactedValue}},
+		}))
+	})
+
+	It("should keep the field order of the struct", func() {
+		var buf bytes.Buffer
+		Expect(config.WriteMasked(&buf, Credentials{Username: "admin", Password: "s3cret"})).Should(Succeed())
+		Expect(buf.String()).To(MatchJSON(`{"username": "admin", "password": "***REDACTED***"}`))
+		Expect(buf.String()).To(MatchRegexp(`(?s)"username".*"password"`))
+	})
+
+	It("should mask untagged fields resolved from a secret prefix", func() {
+		key, err := cryptutil.GenerateKey()
+		Expect(err).Should(BeNil())
+		aes, err := cryptutil.NewAES256(key)
+		Expect(err).Should(BeNil())
+		encrypted, err := aes.EncryptStringToHex("resolved-s3cret")
+		Expect(err).Should(BeNil())
+		GinkgoT().Setenv("TEST_MASKED_TOKEN", encrypted)
+		encrypted, err = aes.EncryptStringToHex("admin")
+		Expect(err).Should(BeNil())
+		GinkgoT().Setenv("TEST_MASKED_USER", encrypted)
+
+		data := `{
+			"name": "service",
+			"regions": {"eu": [{"endpoint": "https://eu.example.com", "auth": {"username": "#EncryptedENV:TEST_MASKED_USER"}}]},
+			"token": "#EncryptedENV:TEST_MASKED_TOKEN"
+		}`
+		var secrets config.Secrets
+		var conf RedactedConfig
+		Expect(config.LoadFromBytes([]byte(data), key, &conf, config.WithSecrets(&secrets))).Should(Succeed())
+		Expect(conf.Token).To(Equal("resolved-s3cret"))
+
+		var buf bytes.Buffer
+		Expect(config.WriteMasked(&buf, conf, config.WithSecrets(&secrets))).Should(Succeed())
+		Expect(buf.String()).NotTo(ContainSubstring("resolved-s3cret"))
+		Expect(buf.String()).To(MatchJSON(`{
+			"name": "service",
+			"regions": {"eu": [{"endpoint": "https://eu.example.com", "auth": {"username": "***REDACTED***", "password": "***REDACTED***"}}]},
+			"token": "***REDACTED***"
+		}`))
+
+		// Equal values in other fields or configs are left alone
+		buf.Reset()
+		other := MaskedConfig{Name: "resolved-s3cret", Database: Credentials{Username: "admin"}}
+		Expect(config.WriteMasked(&buf, other, config.WithSecrets(&secrets))).Should(Succeed())
+		Expect(buf.String()).To(ContainSubstring(`"name": "resolved-s3cret"`))
+		Expect(buf.String()).To(ContainSubstring(`"username": "admin"`))
+	})
+
+	It("should redact deeply nested secrets and secret references for logging", func() {
+		conf := RedactedConfig{Name: "service", Token: "#EncryptedENV:TOKEN"}
+		conf.Regions = map[string][]struct {
+			Endpoint string      `json:"endpoint"`
+			Auth     Credentials `json:"auth"`
+		}{"eu": {{Endpoint: "https://eu.example.com", Auth: Credentials{Username: "admin", Password: "s3cret"}}}}
+
+		redacted, err := config.Redact(&conf)
+		Expect(err).Should(BeNil())
+		Expect(redacted).NotTo(ContainSubstring("s3cret"))
+		Expect(redacted).NotTo(ContainSubstring("TOKEN"))
+		Expect(redacted).To(MatchJSON(`{
+			"name": "service",
+			"regions": {"eu": [{"endpoint": "https://eu.example.com", "auth": {"username": "admin", "password": "***"}}]},
+			"token": "***"
+		}`))
+	})
+
+	It("should redact values resolved from a secret prefix, inline ones included", func() {
+		key, err := cryptutil.GenerateKey()
+		Expect(err).Should(BeNil())
+		aes, err := cryptutil.NewAES256(key)
+		Expect(err).Should(BeNil())
+		encrypted, err := aes.EncryptStringToHex("redacted-s3cret")
+		Expect(err).Should(BeNil())
+		GinkgoT().Setenv("TEST_REDACTED_TOKEN", encrypted)
+
+		conf := RedactedConfig{
+			Name:  "postgres://reader:${#EncryptedENV:TEST_REDACTED_TOKEN}@db:5432",
+			Token: "#EncryptedENV:TEST_REDACTED_TOKEN",
+		}
+		var secrets config.Secrets
+		parser := config.NewParser(key, config.WithSecrets(&secrets))
+		Expect(parser.ProcessStruct(&conf)).Should(Succeed())
+		Expect(conf.Token).To(Equal("redacted-s3cret"))
+
+		redacted, err := config.Redact(&conf, config.WithSecrets(&secrets))
+		Expect(err).Should(BeNil())
+		Expect(redacted).NotTo(ContainSubstring("redacted-s3cret"))
+		Expect(redacted).To(MatchJSON(`{"name": "***", "regions": null, "token": "***"}`))
+
+		// Resolving again replaces the record of the previous resolution
+		conf = RedactedConfig{Name: "service", Token: "#EncryptedENV:TEST_REDACTED_TOKEN"}
+		Expect(parser.ProcessStruct(&conf)).Should(Succeed())
+		redacted, err = config.Redact(&conf, config.WithSecrets(&secrets))
+		Expect(err).Should(BeNil())
+		Expect(redacted).To(MatchJSON(`{"name": "service", "regions": null, "token": "***"}`))
+	})
+
+	It("should dump the resolved values, masking secrets on request", func() {
+		GinkgoT().Setenv("TEST_DUMP_PASSWORD", "s3cret")
+		data := `{"name": "service", "database": {"username": "admin", "password": "#ENV:TEST_DUMP_PASSWORD"}}`
+
+		var conf MaskedConfig
+		Expect(config.LoadFromBytes([]byte(data), "", &conf)).Should(Succeed())
+
+		var buf bytes.Buffer
+		Expect(config.DumpResolved(&conf, &buf)).Should(Succeed())
+		Expect(buf.String()).To(MatchJSON(`{
+			"name": "service",
+			"api_key": "",
+			"database": {"username": "admin", "password": "s3cret"},
+			"replicas": null,
+			"services": null
+		}`))
+		Expect(buf.String()).To(ContainSubstring("\n  \"name\""))
+
+		buf.Reset()
+		Expect(config.DumpResolved(&conf, &buf, config.WithMaskedSecrets())).Should(Succeed())
+		Expect(buf.String()).NotTo(ContainSubstring("s3cret"))
+		Expect(buf.String()).To(ContainSubstring(`"password": "***REDACTED***"`))
+	})
+})
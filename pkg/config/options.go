@@ -0,0 +1,133 @@
+package config
+
+// Options is a functional option type for configuring the Parser and the loaders
+type Options func(*options)
+
+// options holds the configuration options for the Parser and the loaders
+type options struct {
+	maxDepth          int
+	strict            bool
+	envOverridePrefix string
+	missingEnvPolicy  MissingEnvPolicy
+	sliceSeparator    string
+	comments          bool
+	maskSecrets       bool
+	secrets           *Secrets
+	structValidator   StructValidator
+}
+
+// MissingEnvPolicy decides what happens when a reference such as #ENV: points at a value
+// that doesn't exist
+type MissingEnvPolicy int
+
+const (
+	// MissingEnvError fails the resolution, this is the default
+	MissingEnvError MissingEnvPolicy = iota
+
+	// MissingEnvEmpty leaves the field empty and continues
+	MissingEnvEmpty
+
+	// MissingEnvWarn logs the missing value, leaves the field empty and continues
+	MissingEnvWarn
+)
+
+// defaultOptions returns the default options for the Parser and the loaders
+func defaultOptions() *options {
+	return &options{
+		maxDepth:       32,
+		sliceSeparator: ",",
+	}
+}
+
+// applyOptions returns the default options with opts applied on top
+func applyOptions(opts []Options) *options {
+	defaultOpts := defaultOptions()
+	for _, opt := range opts {
+		opt(defaultOpts)
+	}
+	return defaultOpts
+}
+
+// WithMaxDepth sets how deeply nested structs, maps and slices may be before the parser
+// gives up with ErrRecursionLimit.
+func WithMaxDepth(maxDepth int) Options {
+	return func(opts *options) {
+		opts.maxDepth = maxDepth
+	}
+}
+
+// WithStrict makes the loaders reject config files containing keys that don't match any
+// field of the target, so that a misspelled key fails loudly instead of being ignored.
+func WithStrict() Options {
+	return func(opts *options) {
+		opts.strict = true
+	}
+}
+
+// WithEnvOverride lets environment variables override any field after resolution. The variable
+// for a field is the prefix followed by the uppercased, underscore-joined path of the field, so
+// with prefix "APP_" the field Bar.InnerFoo is overridden by APP_BAR_INNER_FOO. Path segments
+// use the JSON name of a field when it has one and its Go name otherwise.
+func WithEnvOverride(prefix string) Options {
+	return func(opts *options) {
+		opts.envOverridePrefix = prefix
+	}
+}
+
+// WithMissingEnvPolicy sets how references to missing environment variables, files and
+// secrets are handled. It applies to every prefix, including the ones of custom resolvers
+// returning an error wrapping ErrNotFound.
+func WithMissingEnvPolicy(policy MissingEnvPolicy) Options {
+	return func(opts *options) {
+		opts.missingEnvPolicy = policy
+	}
+}
+
+// WithComments lets JSON config files contain // line comments, /* block comments */ and
+// trailing commas after the last element of an object or array, as in JSONC.
+func WithComments() Options {
+	return func(opts *options) {
+		opts.comments = true
+	}
+}
+
+// WithMaskedSecrets makes DumpResolved replace secrets by RedactedValue, like WriteMasked.
+func WithMaskedSecrets() Options {
+	return func(opts *options) {
+		opts.maskSecrets = true
+	}
+}
+
+// WithSecrets makes the Parser and the loaders record in secrets the fields they resolve from a
+// secret prefix, and WriteMasked, DumpResolved and Redact hide the fields recorded in it. See
+// Secrets.
+func WithSecrets(secrets *Secrets) Options {
+	return func(opts *options) {
+		opts.secrets = secrets
+	}
+}
+
+// StructValidator validates a struct against declarative rules, such as the `validate` tags
+// checked by the *validator.Validate of github.com/go-playground/validator
+type StructValidator interface {
+	Struct(s any) error
+}
+
+// WithStructValidator makes the loaders run validator on the target once references are
+// resolved, before its Validate method if it has one, and return its error as is. With
+// github.com/go-playground/validator, pass validator.New() and use errors.As to get the
+// validator.ValidationErrors listing every failed field. The package doesn't depend on any
+// validation library, so callers choose and import their own.
+func WithStructValidator(validator StructValidator) Options {
+	return func(opts *options) {
+		opts.structValidator = validator
+	}
+}
+
+// WithSliceSeparator sets the separator used to split a single environment value into the
+// entries of a slice field, such as HOSTS=a,b,c into a []string. It defaults to a comma.
+func WithSliceSeparator(separator string) Options {
+	return func(opts *options) {
+		opts.sliceSeparator = separator
+	}
+}
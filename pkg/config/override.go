@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// applyEnvOverrides replaces the value of every field whose override environment variable is
+// set, descending into nested structs and non-nil struct pointers
+func (p *Parser) applyEnvOverrides(state *walkState, structVal reflect.Value, path, envPrefix string) error {
+	structType := structVal.Type()
+	for i := 0; i < structVal.NumField(); i++ {
+		fieldType := structType.Field(i)
+		if !fieldType.IsExported() && !fieldType.Anonymous {
+			continue
+		}
+
+		field := structVal.Field(i)
+		fieldPath := joinPath(path, fieldType.Name)
+		name, _, _ := strings.Cut(fieldType.Tag.Get("json"), ",")
+
+		// Fields of embedded structs are promoted, so they don't add a segment to the name
+		fieldEnvPrefix := envPrefix
+		if !fieldType.Anonymous || name != "" || reflect.Indirect(field).Kind() != reflect.Struct {
+			if name == "" || name == "-" {
+				name = fieldType.Name
+			}
+			fieldEnvPrefix += envName(name)
+		}
+
+		switch {
+		case field.Kind() == reflect.Struct && !implementsTextUnmarshaler(field):
+			if err := p.applyEnvOverrides(state, field, fieldPath, underscored(fieldEnvPrefix, envPrefix)); err != nil {
+				return err
+			}
+		case field.Kind() == reflect.Ptr && !field.IsNil() && field.Elem().Kind() == reflect.Struct:
+			if state.visit(field) {
+				if err := p.applyEnvOverrides(state, field.Elem(), fieldPath, underscored(fieldEnvPrefix, envPrefix)); err != nil {
+					return err
+				}
+			}
+		case field.CanSet():
+			value, ok := os.LookupEnv(fieldEnvPrefix)
+			if !ok {
+				continue
+			}
+			if err := setFromString(field, value, fieldType.Tag, p.options().sliceSeparator); err != nil {
+				return fieldError(fieldPath, err)
+			}
+		}
+	}
+	return nil
+}
+
+// underscored terminates the env name of a struct with an underscore before its fields are
+// appended, unless it didn't add a segment of its own
+func underscored(name, parent string) string {
+	if name == parent {
+		return name
+	}
+	return name + "_"
+}
+
+// envName converts a field name such as "InnerFoo", "inner_foo" or "APIKey" into the
+// uppercased, underscore separated form used for environment variables
+func envName(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			b.WriteByte('_')
+			continue
+		}
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package config
+
+import "sync/atomic"
+
+// Reloadable holds a config value that can be replaced while other goroutines read it. Every
+// Store publishes a complete value with a single atomic swap, so readers observe either the
+// previous value or the new one and never a partially updated struct.
+//
+// Values must be treated as immutable once stored, since Load hands out shallow copies that
+// share maps, slices and pointers with the stored value.
+type Reloadable[T any] struct {
+	value atomic.Pointer[T]
+}
+
+// NewReloadable returns a Reloadable holding value
+func NewReloadable[T any](value T) *Reloadable[T] {
+	r := &Reloadable[T]{}
+	r.Store(value)
+	return r
+}
+
+// Load returns the current value, or the zero value of T if nothing has been stored yet
+func (r *Reloadable[T]) Load() T {
+	if value := r.value.Load(); value != nil {
+		return *value
+	}
+	var zero T
+	return zero
+}
+
+// Store replaces the current value
+func (r *Reloadable[T]) Store(value T) {
+	r.value.Store(&value)
+}
+
+// LoadReloadable loads the JSON config file into a new Reloadable and keeps it up to date by
+// watching the file, as Watch does. A failed reload leaves the previous value in place and
+// is reported to onReload, which may be nil.
+//
+// The returned stop function stops watching; the Reloadable keeps its last value.
+func LoadReloadable[T any](filePath, secret string, onReload func(error), opts ...Options) (*Reloadable[T], func(), error) {
+	var target T
+	if err := LoadFromFile(filePath, secret, &target, opts...); err != nil {
+		return nil, nil, err
+	}
+	r := NewReloadable(target)
+
+	// target is only touched by the watcher goroutine from here on, and every reload assigns
+	// a freshly loaded value to it, so the copies stored never share state with later loads
+	stop, err := Watch(filePath, secret, &target, func(err error) {
+		if err == nil {
+			r.Store(target)
+		}
+		if onReload != nil {
+			onReload(err)
+		}
+	}, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, stop, nil
+}
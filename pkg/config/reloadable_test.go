@@ -0,0 +1,84 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/catalogfi/tools/pkg/config"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// pair is consistent when both fields hold the same generation
+type pair struct {
+	First  int
+	Second int
+	Name   string
+}
+
+var _ = Describe("Reloadable", func() {
+	It("should return the zero value before anything is stored", func() {
+		var r config.Reloadable[Config]
+		Expect(r.Load()).To(Equal(Config{}))
+	})
+
+	It("should never expose a half-updated value to concurrent readers", func() {
+		r := config.NewReloadable(pair{Name: "0"})
+
+		const generations = 2000
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		inconsistent := make(chan pair, 1)
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-done:
+						return
+					default:
+					}
+					if p := r.Load(); p.First != p.Second {
+						select {
+						case inconsistent <- p:
+						default:
+						}
+					}
+				}
+			}()
+		}
+
+		for i := 1; i <= generations; i++ {
+			r.Store(pair{First: i, Second: i, Name: "gen"})
+		}
+		close(done)
+		wg.Wait()
+
+		Expect(inconsistent).NotTo(Receive())
+		Expect(r.Load().First).To(Equal(generations))
+	})
+
+	It("should load the file and follow its changes", func() {
+		filePath := filepath.Join(GinkgoT().TempDir(), "config.json")
+		Expect(os.WriteFile(filePath, []byte(`{"foo": "1"}`), 0644)).Should(Succeed())
+
+		r, stop, err := config.LoadReloadable[Config](filePath, "", nil)
+		Expect(err).Should(BeNil())
+		defer stop()
+		Expect(r.Load().Foo).To(Equal("1"))
+
+		Expect(os.WriteFile(filePath, []byte(`{"foo": "2"}`), 0644)).Should(Succeed())
+		Eventually(func() string { return r.Load().Foo }, 5*time.Second).Should(Equal("2"))
+	})
+
+	It("should return the load error when the initial load fails", func() {
+		filePath := filepath.Join(GinkgoT().TempDir(), "missing.json")
+
+		_, _, err := config.LoadReloadable[Config](filePath, "", nil)
+		Expect(err).Should(HaveOccurred())
+	})
+})
@@ -0,0 +1,49 @@
+package config
+
+import "sync"
+
+// secretPrefixes are the prefixes of references to secrets. The fields resolved through them are
+// recorded in Secrets, and Redact hides strings that still hold one of them.
+var secretPrefixes = []string{EncryptedEnvPrefix, EncryptedFilePrefix, VaultPrefix, AWSPrefix}
+
+// isSecretPrefix reports whether prefix is one of the secretPrefixes
+func isSecretPrefix(prefix string) bool {
+	for _, secretPrefix := range secretPrefixes {
+		if prefix == secretPrefix {
+			return true
+		}
+	}
+	return false
+}
+
+// Secrets records the fields of a config that were resolved from a secret prefix, one of
+// #EncryptedENV:, #EncryptedFILE:, #VAULT: and #AWS:, including fields embedding such a reference
+// inline, so that WriteMasked, DumpResolved and Redact can hide them even when they aren't tagged
+// with `secret:"true"`. Give the same Secrets to the loader or Parser and to the masking functions
+// with WithSecrets.
+//
+// Fields are recorded by their path in the target, and every successful resolution replaces
+// what the previous one recorded, so a Secrets must only be used for a single target, which may
+// be reloaded. The zero value is ready to use.
+type Secrets struct {
+	mu    sync.RWMutex
+	paths map[string]struct{}
+}
+
+// replace records paths in place of the fields recorded so far
+func (s *Secrets) replace(paths map[string]struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paths = paths
+}
+
+// contains reports whether the field at path was resolved from a secret prefix
+func (s *Secrets) contains(path string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.paths[path]
+	return ok
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+)
+
+// LoadFromTOML loads the TOML config file into target and resolves environment references,
+// like LoadFromFile does for JSON. As with LoadFromYAML, the document is converted to JSON
+// before it is decoded, so fields are matched by their json tags. Syntax errors report the line
+// they were found on.
+func LoadFromTOML(filePath, secret string, target any, opts ...Options) error {
+	file, err := readFile(filePath)
+	if err != nil {
+		return err
+	}
+	data, err := tomlToJSON(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+	return LoadFromBytes(data, secret, target, opts...)
+}
+
+// tomlToJSON converts a TOML document into the equivalent JSON document. The errors of the
+// TOML decoder are toml.ParseError values, whose message starts with the line of the error.
+func tomlToJSON(data []byte) ([]byte, error) {
+	var doc map[string]any
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
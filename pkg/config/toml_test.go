@@ -0,0 +1,62 @@
+package config_test
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"github.com/catalogfi/tools/pkg/config"
+	"github.com/catalogfi/tools/pkg/cryptutil"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TOML", func() {
+	var path string
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "config.toml")
+	})
+
+	It("should load the config and decrypt encrypted references", func() {
+		key := make([]byte, 32)
+		_, err := rand.Read(key)
+		Expect(err).Should(BeNil())
+		aes, err := cryptutil.NewAES256(hex.EncodeToString(key))
+		Expect(err).Should(BeNil())
+		encrypted, err := aes.EncryptStringToHex("s3cret")
+		Expect(err).Should(BeNil())
+		GinkgoT().Setenv("TEST_TOML_ENCRYPTED", encrypted)
+
+		data := `
+foo = "1"
+
+[bar]
+inner_foo = "#EncryptedENV:TEST_TOML_ENCRYPTED"
+inner_bar = "3"
+`
+		Expect(os.WriteFile(path, []byte(data), 0644)).Should(Succeed())
+
+		var conf Config
+		Expect(config.LoadFromTOML(path, hex.EncodeToString(key), &conf)).Should(Succeed())
+		Expect(conf.Foo).To(Equal("1"))
+		Expect(conf.Bar.InnerFoo).To(Equal("s3cret"))
+		Expect(conf.Bar.InnerBar).To(Equal("3"))
+	})
+
+	It("should report the line of a syntax error", func() {
+		Expect(os.WriteFile(path, []byte("foo = \"1\"\n\nbar = 1 2\n"), 0644)).Should(Succeed())
+
+		var conf Config
+		err := config.LoadFromTOML(path, "", &conf)
+		Expect(err).To(MatchError(ContainSubstring("line 3")))
+
+		var parseErr toml.ParseError
+		Expect(errors.As(err, &parseErr)).To(BeTrue())
+		Expect(parseErr.Position.Line).To(Equal(3))
+	})
+})
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// LoadFromURL fetches the config served at configURL with a GET request bounded by ctx, then
+// loads it into target like LoadFromBytesContext. The format is taken from the Content-Type of
+// the response, then from the extension of the URL path as in LoadAuto, and defaults to JSON.
+// Responses with a status other than 200 OK fail without loading anything.
+func LoadFromURL(ctx context.Context, configURL, secret string, target any, opts ...Options) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, configURL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid config url: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch config: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch config from %s: unexpected status %s", req.URL.Redacted(), resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read config from %s: %w", req.URL.Redacted(), err)
+	}
+
+	data, err := toJSON(remoteFileName(req.URL, resp.Header.Get("Content-Type")), body)
+	if err != nil {
+		return fmt.Errorf("failed to parse config from %s: %w", req.URL.Redacted(), err)
+	}
+	return LoadFromBytesContext(ctx, data, secret, target, opts...)
+}
+
+// remoteFileName returns a file name whose extension matches the format of a fetched config,
+// going by its media type first and by the path of its URL otherwise
+func remoteFileName(u *url.URL, contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return "config.yaml"
+	case "application/toml", "text/toml":
+		return "config.toml"
+	case "application/json":
+		return "config.json"
+	default:
+		return path.Base(u.Path)
+	}
+}
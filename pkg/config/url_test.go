@@ -0,0 +1,70 @@
+package config_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/catalogfi/tools/pkg/config"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("URL", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		GinkgoT().Setenv("TestKey", "2")
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/config":
+				_, _ = w.Write([]byte(`{"foo": "1", "bar": {"inner_foo": "#ENV:TestKey"}}`))
+			case "/config.yaml":
+				_, _ = w.Write([]byte("foo: \"1\"\nbar:\n  inner_foo: \"#ENV:TestKey\"\n"))
+			case "/typed":
+				w.Header().Set("Content-Type", "application/toml; charset=utf-8")
+				_, _ = w.Write([]byte("foo = \"1\"\n[bar]\ninner_foo = \"#ENV:TestKey\"\n"))
+			case "/slow":
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	DescribeTable("should load and resolve the served config",
+		func(path string) {
+			var conf Config
+			Expect(config.LoadFromURL(context.Background(), server.URL+path, "", &conf)).Should(Succeed())
+			Expect(conf.Foo).To(Equal("1"))
+			Expect(conf.Bar.InnerFoo).To(Equal("2"))
+		},
+		Entry("as JSON by default", "/config"),
+		Entry("by the extension of the path", "/config.yaml"),
+		Entry("by the content type", "/typed"),
+	)
+
+	It("should report an unexpected status", func() {
+		var conf Config
+		err := config.LoadFromURL(context.Background(), server.URL+"/missing", "", &conf)
+		Expect(err).To(MatchError(ContainSubstring("unexpected status 404 Not Found")))
+	})
+
+	It("should respect the context deadline", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		var conf Config
+		err := config.LoadFromURL(ctx, server.URL+"/slow", "", &conf)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
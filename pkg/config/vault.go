@@ -0,0 +1,96 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// VaultPrefix is used for secrets stored in a HashiCorp Vault KV v2 engine, e.g.
+// "#VAULT:secret/data/db#password" reads the "password" key at "secret/data/db"
+const VaultPrefix = "#VAULT:"
+
+// VaultResolver fetches secrets from a HashiCorp Vault KV v2 engine over its HTTP API
+type VaultResolver struct {
+	// Address is the Vault server address, falling back to VAULT_ADDR when empty
+	Address string
+
+	// Token is the Vault token used to authenticate, falling back to VAULT_TOKEN when empty
+	Token string
+
+	// Client is the HTTP client used for requests, falling back to http.DefaultClient when nil
+	Client *http.Client
+}
+
+// vaultResponse is the relevant part of a KV v2 read response
+type vaultResponse struct {
+	Data struct {
+		Data map[string]any `json:"data"`
+	} `json:"data"`
+}
+
+// Resolve reads the secret referenced by "<path>#<key>" and returns the value of the key
+func (v *VaultResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("invalid vault reference %q, expected <path>#<key>", ref)
+	}
+
+	address := v.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return "", fmt.Errorf("vault address not configured for %s, set VAULT_ADDR", path)
+	}
+	token := v.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	endpoint, err := url.JoinPath(address, "v1", path)
+	if err != nil {
+		return "", fmt.Errorf("invalid vault address %s: %w", address, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create vault request for %s: %w", path, err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch vault secret %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("vault secret %s %w", path, ErrNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch vault secret %s: unexpected status %s", path, resp.Status)
+	}
+
+	var body vaultResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault secret %s: %w", path, err)
+	}
+
+	value, ok := body.Data.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key %s in vault secret %s %w", key, path, ErrNotFound)
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("key %s in vault secret %s is not a string", key, path)
+	}
+	return str, nil
+}
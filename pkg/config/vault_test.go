@@ -0,0 +1,81 @@
+package config_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/catalogfi/tools/pkg/config"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Vault", func() {
+	type VaultConfig struct {
+		Password string
+	}
+
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Vault-Token") != "test-token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			if r.URL.Path != "/v1/secret/data/db" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"data":{"password":"s3cret"},"metadata":{"version":1}}}`))
+		}))
+		GinkgoT().Setenv("VAULT_ADDR", server.URL)
+		GinkgoT().Setenv("VAULT_TOKEN", "test-token")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should resolve a secret from vault", func() {
+		conf := VaultConfig{Password: config.VaultPrefix + "secret/data/db#password"}
+		Expect(config.NewParser("").ProcessStruct(&conf)).Should(Succeed())
+		Expect(conf.Password).To(Equal("s3cret"))
+	})
+
+	It("should return an error when the key does not exist", func() {
+		conf := VaultConfig{Password: config.VaultPrefix + "secret/data/db#username"}
+		err := config.NewParser("").ProcessStruct(&conf)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("username"))
+	})
+
+	It("should return an error identifying the path when vault responds with an error", func() {
+		conf := VaultConfig{Password: config.VaultPrefix + "secret/data/other#password"}
+		err := config.NewParser("").ProcessStruct(&conf)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("secret/data/other"))
+	})
+
+	It("should return a wrapped error identifying the path when vault is unreachable", func() {
+		server.Close()
+
+		conf := VaultConfig{Password: config.VaultPrefix + "secret/data/db#password"}
+		err := config.NewParser("").ProcessStruct(&conf)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("secret/data/db"))
+	})
+
+	It("should use a resolver registered on the parser", func() {
+		parser := config.NewParser("")
+		parser.RegisterResolver(config.VaultPrefix, &config.VaultResolver{
+			Address: server.URL,
+			Token:   "test-token",
+		})
+		GinkgoT().Setenv("VAULT_TOKEN", "wrong-token")
+
+		conf := VaultConfig{Password: config.VaultPrefix + "secret/data/db#password"}
+		Expect(parser.ProcessStruct(&conf)).Should(Succeed())
+		Expect(conf.Password).To(Equal("s3cret"))
+	})
+})
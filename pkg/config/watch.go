@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch reloads the JSON config file into target every time it changes and calls onReload
+// with the result. Each reload is loaded and resolved into a fresh value first, so target is
+// only replaced when the whole load succeeds; on failure target keeps its previous value and
+// onReload receives the error.
+//
+// The replacement is a plain assignment performed on the watcher goroutine right before
+// onReload is called. Callers reading target from other goroutines must synchronize
+// themselves, for example by copying target inside onReload while holding their own lock.
+//
+// The returned stop function stops watching and waits for any in-flight reload to finish,
+// so onReload is never called after stop returns.
+func Watch(filePath, secret string, target any, onReload func(error), opts ...Options) (stop func(), err error) {
+	targetVal := reflect.ValueOf(target)
+	if targetVal.Kind() != reflect.Ptr || targetVal.IsNil() {
+		return nil, fmt.Errorf("expected non-nil pointer, got %T", target)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+	// Watch the directory rather than the file, since editors and Kubernetes replace files
+	// by renaming which would drop a watch on the file itself
+	if err := watcher.Add(filepath.Dir(filePath)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch %s: %w", filePath, err)
+	}
+
+	fileName := filepath.Clean(filePath)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != fileName || !event.Has(fsnotify.Write|fsnotify.Create) {
+					continue
+				}
+
+				fresh := reflect.New(targetVal.Elem().Type())
+				err := LoadFromFile(filePath, secret, fresh.Interface(), opts...)
+				if err == nil {
+					targetVal.Elem().Set(fresh.Elem())
+				}
+				onReload(err)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				onReload(err)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-exited
+			watcher.Close()
+		})
+	}, nil
+}
@@ -0,0 +1,63 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/catalogfi/tools/pkg/config"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Watch", func() {
+	It("should reload the config when the file changes", func() {
+		filePath := filepath.Join(GinkgoT().TempDir(), "config.json")
+		Expect(os.WriteFile(filePath, []byte(`{"foo": "1"}`), 0644)).Should(Succeed())
+
+		var conf Config
+		Expect(config.LoadFromFile(filePath, "", &conf)).Should(Succeed())
+
+		reloaded := make(chan Config, 16)
+		stop, err := config.Watch(filePath, "", &conf, func(err error) {
+			if err == nil {
+				reloaded <- conf
+			}
+		})
+		Expect(err).Should(BeNil())
+		defer stop()
+
+		Expect(os.WriteFile(filePath, []byte(`{"foo": "2"}`), 0644)).Should(Succeed())
+
+		var latest Config
+		Eventually(reloaded, 5*time.Second).Should(Receive(&latest))
+		Expect(latest.Foo).To(Equal("2"))
+	})
+
+	It("should report load errors and keep the previous value", func() {
+		filePath := filepath.Join(GinkgoT().TempDir(), "config.json")
+		Expect(os.WriteFile(filePath, []byte(`{"foo": "1"}`), 0644)).Should(Succeed())
+
+		var conf Config
+		Expect(config.LoadFromFile(filePath, "", &conf)).Should(Succeed())
+
+		type result struct {
+			err  error
+			conf Config
+		}
+		reloaded := make(chan result, 16)
+		stop, err := config.Watch(filePath, "", &conf, func(err error) {
+			reloaded <- result{err: err, conf: conf}
+		})
+		Expect(err).Should(BeNil())
+		defer stop()
+
+		Expect(os.WriteFile(filePath, []byte(`{"foo": "#ENV:TEST_WATCH_MISSING"}`), 0644)).Should(Succeed())
+
+		var latest result
+		Eventually(reloaded, 5*time.Second).Should(Receive(&latest))
+		Expect(latest.err).Should(HaveOccurred())
+		Expect(latest.conf.Foo).To(Equal("1"))
+	})
+})
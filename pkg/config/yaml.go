@@ -0,0 +1,57 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFromYAML loads the YAML config file into target and resolves environment references,
+// like LoadFromFile does for JSON. The document is converted to JSON before it is decoded, so
+// fields are matched by their json tags and the same structs can be loaded from either format.
+func LoadFromYAML(filePath, secret string, target any, opts ...Options) error {
+	file, err := readFile(filePath)
+	if err != nil {
+		return err
+	}
+	data, err := yamlToJSON(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+	return LoadFromBytes(data, secret, target, opts...)
+}
+
+// yamlToJSON converts a YAML document into the equivalent JSON document
+func yamlToJSON(data []byte) ([]byte, error) {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonCompatible(doc))
+}
+
+// jsonCompatible converts the mappings with non-string keys decoded by yaml.v3, such as the
+// ones using integers as keys, into objects that encoding/json can marshal
+func jsonCompatible(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, elem := range v {
+			v[key] = jsonCompatible(elem)
+		}
+		return v
+	case map[any]any:
+		obj := make(map[string]any, len(v))
+		for key, elem := range v {
+			obj[fmt.Sprint(key)] = jsonCompatible(elem)
+		}
+		return obj
+	case []any:
+		for i, elem := range v {
+			v[i] = jsonCompatible(elem)
+		}
+		return v
+	default:
+		return v
+	}
+}
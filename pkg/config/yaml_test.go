@@ -0,0 +1,60 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/catalogfi/tools/pkg/config"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("YAML", func() {
+	var path string
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+	})
+
+	It("should load the config and resolve references in nested mappings", func() {
+		data := `
+foo: "1"
+bar:
+  inner_foo: "#ENV:TestKey"
+  inner_bar: "3"
+`
+		Expect(os.WriteFile(path, []byte(data), 0644)).Should(Succeed())
+		GinkgoT().Setenv("TestKey", "2")
+
+		var conf Config
+		Expect(config.LoadFromYAML(path, "", &conf)).Should(Succeed())
+		Expect(conf.Foo).To(Equal("1"))
+		Expect(conf.Bar.InnerFoo).To(Equal("2"))
+		Expect(conf.Bar.InnerBar).To(Equal("3"))
+	})
+
+	It("should decode mappings with non-string keys", func() {
+		Expect(os.WriteFile(path, []byte("ports:\n  80: http\n  443: https\n"), 0644)).Should(Succeed())
+
+		var conf struct {
+			Ports map[int]string `json:"ports"`
+		}
+		Expect(config.LoadFromYAML(path, "", &conf)).Should(Succeed())
+		Expect(conf.Ports).To(Equal(map[int]string{80: "http", 443: "https"}))
+	})
+
+	It("should reject unknown keys in strict mode", func() {
+		Expect(os.WriteFile(path, []byte("foo: \"1\"\nfooo: \"2\"\n"), 0644)).Should(Succeed())
+
+		var conf Config
+		Expect(config.LoadFromYAML(path, "", &conf, config.WithStrict())).To(MatchError(ContainSubstring("fooo")))
+	})
+
+	It("should report invalid YAML", func() {
+		Expect(os.WriteFile(path, []byte("foo: [1, 2\n"), 0644)).Should(Succeed())
+
+		var conf Config
+		Expect(config.LoadFromYAML(path, "", &conf)).To(MatchError(ContainSubstring("failed to parse")))
+	})
+})
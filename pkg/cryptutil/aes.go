@@ -5,6 +5,7 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -32,6 +33,20 @@ func hexDecode(hexData string) ([]byte, error) {
 	return data, nil
 }
 
+// base64Decode decodes a standard base64 string into bytes.
+func base64Decode(base64Data string) ([]byte, error) {
+	if base64Data == "" {
+		return nil, ErrEmptyData
+	}
+
+	data, err := base64.StdEncoding.DecodeString(base64Data)
+	if err != nil {
+		return nil, fmt.Errorf("cryptutil: invalid base64 data: %w", err)
+	}
+
+	return data, nil
+}
+
 // DataEncryptor defines operations for encrypting data.
 type DataEncryptor interface {
 	// Encrypt takes plaintext data and returns encrypted data.
@@ -51,6 +66,15 @@ type AES256 struct {
 	key []byte
 }
 
+// GenerateKey generates a new random AES-256 key, hex encoded for NewAES256.
+func GenerateKey() (string, error) {
+	key := make([]byte, 32) // 32 bytes = 256 bits
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("cryptutil: failed to generate key: %w", err)
+	}
+	return hex.EncodeToString(key), nil
+}
+
 // NewAES256 creates a new AES-256 encryption/decryption provider from a hex encoded key.
 // The key must be exactly 32 bytes (64 hex characters) for AES-256.
 func NewAES256(hexKey string) (*AES256, error) {
@@ -124,6 +148,22 @@ func (a *AES256) EncryptStringToHex(plaintext string) (string, error) {
 	return hex.EncodeToString(encrypted), nil
 }
 
+// EncryptToBase64 encrypts data and returns it as a standard base64 string.
+// It is the base64 counterpart of EncryptToHex, for tools that expect base64.
+func (a *AES256) EncryptToBase64(plaintext []byte) (string, error) {
+	encrypted, err := a.Encrypt(plaintext)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+// EncryptStringToBase64 encrypts a string and returns it as a standard base64 string.
+// It's a convenient combination of EncryptString and base64 encoding.
+func (a *AES256) EncryptStringToBase64(plaintext string) (string, error) {
+	return a.EncryptToBase64([]byte(plaintext))
+}
+
 // Decrypt decrypts data using AES-256-GCM.
 // It expects the nonce to be prepended to the ciphertext as produced by Encrypt.
 func (a *AES256) Decrypt(data []byte) ([]byte, error) {
@@ -184,3 +224,23 @@ func (a *AES256) DecryptHexToString(hexData string) (string, error) {
 	}
 	return a.DecryptToString(data)
 }
+
+// DecryptBase64 decrypts a standard base64 string to bytes.
+// It first decodes the base64 string and then decrypts the result.
+func (a *AES256) DecryptBase64(base64Data string) ([]byte, error) {
+	data, err := base64Decode(base64Data)
+	if err != nil {
+		return nil, err
+	}
+	return a.Decrypt(data)
+}
+
+// DecryptBase64ToString decrypts a standard base64 string to a string.
+// It's a convenient combination of DecryptBase64 and string conversion.
+func (a *AES256) DecryptBase64ToString(base64Data string) (string, error) {
+	data, err := base64Decode(base64Data)
+	if err != nil {
+		return "", err
+	}
+	return a.DecryptToString(data)
+}
@@ -3,6 +3,7 @@ package cryptutil_test
 
 import (
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/hex"
 	"testing"
 
@@ -172,6 +173,52 @@ func TestDecryptionErrors(t *testing.T) {
 	}
 }
 
+// TestBase64 verifies the base64 round trip and the rejection of malformed base64.
+func TestBase64(t *testing.T) {
+	// Create AES encryptor
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	aes, err := cryptutil.NewAES256(hex.EncodeToString(key))
+	require.NoError(t, err)
+
+	encoded, err := aes.EncryptStringToBase64("test data for base64")
+	require.NoError(t, err)
+	_, err = base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err, "output should be standard base64")
+
+	decrypted, err := aes.DecryptBase64ToString(encoded)
+	require.NoError(t, err)
+	require.Equal(t, "test data for base64", decrypted)
+
+	encoded, err = aes.EncryptToBase64([]byte{0x00, 0xff})
+	require.NoError(t, err)
+	raw, err := aes.DecryptBase64(encoded)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x00, 0xff}, raw)
+
+	// Invalid and empty base64
+	_, err = aes.DecryptBase64("not base64!")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid base64")
+	_, err = aes.DecryptBase64ToString("")
+	require.ErrorIs(t, err, cryptutil.ErrEmptyData)
+}
+
+// TestGenerateKey verifies that generated keys are valid and distinct.
+func TestGenerateKey(t *testing.T) {
+	key, err := cryptutil.GenerateKey()
+	require.NoError(t, err)
+	require.Len(t, key, 64)
+
+	_, err = cryptutil.NewAES256(key)
+	require.NoError(t, err)
+
+	other, err := cryptutil.GenerateKey()
+	require.NoError(t, err)
+	require.NotEqual(t, key, other)
+}
+
 // TestInterface verifies that AES256 implements the DataEncryptor and DataDecryptor interfaces.
 func TestInterface(t *testing.T) {
 	// Generate a random key
@@ -0,0 +1,107 @@
+package cryptutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// BackupSuffix is appended to the name of a file to get the name of its backup when it is processed in place.
+const BackupSuffix = ".bak"
+
+// ErrBackupExists is returned when a file can't be processed in place because its backup already exists.
+var ErrBackupExists = errors.New("cryptutil: backup file already exists")
+
+// EncryptFileInPlace encrypts the file at path with EncryptStream, replacing its content with the ciphertext.
+// The original file is first copied to path + BackupSuffix, and the function refuses to run if that backup
+// already exists so an earlier backup is never overwritten. The file is replaced atomically and keeps its
+// permissions. If encryption fails, the file is left untouched and the backup is removed.
+func EncryptFileInPlace(a *AES256, path string) error {
+	return processFileInPlace(path, a.EncryptStream)
+}
+
+// DecryptFileInPlace reverses EncryptFileInPlace, replacing the ciphertext in the file at path with the plaintext.
+// The ciphertext is backed up the same way first.
+func DecryptFileInPlace(a *AES256, path string) error {
+	return processFileInPlace(path, a.DecryptStream)
+}
+
+// processFileInPlace backs up the file at path and replaces it with the output of process.
+func processFileInPlace(path string, process func(dst io.Writer, src io.Reader) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("cryptutil: %w", err)
+	}
+
+	backupPath := path + BackupSuffix
+	if err := copyFile(backupPath, path, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	if err := replaceFile(path, backupPath, info.Mode().Perm(), process); err != nil {
+		os.Remove(backupPath)
+		return err
+	}
+	return nil
+}
+
+// copyFile copies src to a new file dst, failing with ErrBackupExists if dst already exists.
+func copyFile(dst, src string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("cryptutil: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%w: %s", ErrBackupExists, dst)
+	}
+	if err != nil {
+		return fmt.Errorf("cryptutil: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("cryptutil: failed to write backup: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("cryptutil: failed to write backup: %w", err)
+	}
+	return nil
+}
+
+// replaceFile writes the output of process applied to src to a temporary file next to path, then renames it over
+// path.
+func replaceFile(path, src string, perm os.FileMode, process func(dst io.Writer, src io.Reader) error) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("cryptutil: %w", err)
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("cryptutil: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := process(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cryptutil: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cryptutil: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("cryptutil: %w", err)
+	}
+	return nil
+}
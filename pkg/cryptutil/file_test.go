@@ -0,0 +1,66 @@
+package cryptutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/catalogfi/tools/pkg/cryptutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFileInPlace verifies that a file encrypted in place is backed up and decrypts back to its original bytes.
+func TestFileInPlace(t *testing.T) {
+	aes, err := cryptutil.NewAES256(testKeyHex)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	original := []byte(`{"password": "hunter2"}`)
+	require.NoError(t, os.WriteFile(path, original, 0640))
+
+	require.NoError(t, cryptutil.EncryptFileInPlace(aes, path))
+	backup, err := os.ReadFile(path + cryptutil.BackupSuffix)
+	require.NoError(t, err)
+	require.Equal(t, original, backup)
+	encrypted, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(encrypted), "hunter2")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	// The backup of the original must not be overwritten
+	err = cryptutil.DecryptFileInPlace(aes, path)
+	require.ErrorIs(t, err, cryptutil.ErrBackupExists)
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, encrypted, current)
+
+	require.NoError(t, os.Remove(path+cryptutil.BackupSuffix))
+	require.NoError(t, cryptutil.DecryptFileInPlace(aes, path))
+	decrypted, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, original, decrypted)
+	backup, err = os.ReadFile(path + cryptutil.BackupSuffix)
+	require.NoError(t, err)
+	require.Equal(t, encrypted, backup)
+}
+
+// TestFileInPlaceFailure verifies that a failed decryption leaves the file untouched and no backup behind.
+func TestFileInPlaceFailure(t *testing.T) {
+	aes, err := cryptutil.NewAES256(testKeyHex)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plain.txt")
+	require.NoError(t, os.WriteFile(path, []byte("not encrypted"), 0600))
+
+	require.Error(t, cryptutil.DecryptFileInPlace(aes, path))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "not encrypted", string(content))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
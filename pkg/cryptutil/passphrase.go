@@ -0,0 +1,52 @@
+package cryptutil
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// SaltSize is the size in bytes of the salts generated by GenerateSalt, and the minimum accepted by
+// NewAES256FromPassphrase.
+const SaltSize = 16
+
+// Argon2id parameters, following the second recommended option of RFC 9106 for memory-constrained environments.
+// Changing them changes the derived keys, so data encrypted with the previous ones can no longer be decrypted.
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024 // KiB
+	argon2Threads = 4
+)
+
+// Errors returned by the passphrase based constructor.
+var (
+	ErrEmptyPassphrase   = errors.New("cryptutil: empty passphrase")
+	ErrInvalidSaltLength = fmt.Errorf("cryptutil: invalid salt length, must be at least %d bytes", SaltSize)
+)
+
+// GenerateSalt returns a new random salt of SaltSize bytes for NewAES256FromPassphrase.
+func GenerateSalt() ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("cryptutil: failed to generate salt: %w", err)
+	}
+	return salt, nil
+}
+
+// NewAES256FromPassphrase creates a new AES-256 encryption/decryption provider with a key derived from a
+// passphrase with Argon2id. The salt must be random, unique to the data it protects and stored alongside it,
+// since the same passphrase and salt are needed to derive the key again for decryption.
+// Deriving a key is deliberately slow and memory hungry, so reuse the returned instance when possible.
+func NewAES256FromPassphrase(passphrase, salt []byte) (*AES256, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrEmptyPassphrase
+	}
+	if len(salt) < SaltSize {
+		return nil, ErrInvalidSaltLength
+	}
+
+	key := argon2.IDKey(passphrase, salt, argon2Time, argon2Memory, argon2Threads, 32)
+	return &AES256{key: key}, nil
+}
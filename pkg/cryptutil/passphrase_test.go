@@ -0,0 +1,43 @@
+package cryptutil_test
+
+import (
+	"testing"
+
+	"github.com/catalogfi/tools/pkg/cryptutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPassphrase verifies that the same passphrase and salt derive the same key, and that anything else doesn't.
+func TestPassphrase(t *testing.T) {
+	salt, err := cryptutil.GenerateSalt()
+	require.NoError(t, err)
+	require.Len(t, salt, cryptutil.SaltSize)
+
+	aes, err := cryptutil.NewAES256FromPassphrase([]byte("correct horse battery staple"), salt)
+	require.NoError(t, err)
+	encrypted, err := aes.EncryptStringToHex("test data for passphrases")
+	require.NoError(t, err)
+
+	same, err := cryptutil.NewAES256FromPassphrase([]byte("correct horse battery staple"), salt)
+	require.NoError(t, err)
+	decrypted, err := same.DecryptHexToString(encrypted)
+	require.NoError(t, err)
+	require.Equal(t, "test data for passphrases", decrypted)
+
+	wrongPassphrase, err := cryptutil.NewAES256FromPassphrase([]byte("incorrect horse battery staple"), salt)
+	require.NoError(t, err)
+	_, err = wrongPassphrase.DecryptHexToString(encrypted)
+	require.Error(t, err)
+
+	otherSalt, err := cryptutil.GenerateSalt()
+	require.NoError(t, err)
+	wrongSalt, err := cryptutil.NewAES256FromPassphrase([]byte("correct horse battery staple"), otherSalt)
+	require.NoError(t, err)
+	_, err = wrongSalt.DecryptHexToString(encrypted)
+	require.Error(t, err)
+
+	_, err = cryptutil.NewAES256FromPassphrase(nil, salt)
+	require.ErrorIs(t, err, cryptutil.ErrEmptyPassphrase)
+	_, err = cryptutil.NewAES256FromPassphrase([]byte("passphrase"), salt[:8])
+	require.ErrorIs(t, err, cryptutil.ErrInvalidSaltLength)
+}
@@ -0,0 +1,154 @@
+package cryptutil
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Streams are encrypted in chunks so that inputs of any size can be processed in constant memory. Each chunk is
+// sealed with AES-256-GCM under a nonce made of a random prefix written once at the start of the stream, the
+// chunk counter and a flag marking the last chunk, which detects reordered, dropped and truncated chunks.
+const (
+	// streamChunkSize is the size of the plaintext of every chunk but the last
+	streamChunkSize = 64 * 1024
+	// streamPrefixSize is the size of the random nonce prefix at the start of the stream
+	streamPrefixSize = 7
+)
+
+// ErrTruncatedStream is returned when an encrypted stream ends before its last chunk.
+var ErrTruncatedStream = errors.New("cryptutil: truncated stream")
+
+// newGCM creates the AES-256-GCM cipher of the key.
+func (a *AES256) newGCM() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(a.key)
+	if err != nil {
+		return nil, fmt.Errorf("cryptutil: failed to create cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cryptutil: failed to create GCM mode: %w", err)
+	}
+	return gcm, nil
+}
+
+// streamNonce returns the nonce of the chunk with the given counter.
+func streamNonce(nonce, prefix []byte, counter uint32, last bool) []byte {
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[streamPrefixSize:], counter)
+	nonce[len(nonce)-1] = 0
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+// EncryptStream encrypts everything read from src and writes the result to dst, in constant memory.
+// Unlike Encrypt, an empty input is allowed. The output can only be decrypted by DecryptStream.
+func (a *AES256) EncryptStream(dst io.Writer, src io.Reader) error {
+	gcm, err := a.newGCM()
+	if err != nil {
+		return err
+	}
+
+	prefix := make([]byte, streamPrefixSize)
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return fmt.Errorf("cryptutil: failed to generate nonce: %w", err)
+	}
+	if _, err := dst.Write(prefix); err != nil {
+		return fmt.Errorf("cryptutil: failed to write stream: %w", err)
+	}
+
+	reader := bufio.NewReaderSize(src, streamChunkSize)
+	plaintext := make([]byte, streamChunkSize)
+	ciphertext := make([]byte, 0, streamChunkSize+gcm.Overhead())
+	nonce := make([]byte, gcm.NonceSize())
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(reader, plaintext)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("cryptutil: failed to read stream: %w", err)
+		}
+		// A full chunk is the last one only when nothing follows it
+		last := n < streamChunkSize
+		if !last {
+			if _, err := reader.Peek(1); err == io.EOF {
+				last = true
+			}
+		}
+
+		ciphertext = gcm.Seal(ciphertext[:0], streamNonce(nonce, prefix, counter, last), plaintext[:n], nil)
+		if _, err := dst.Write(ciphertext); err != nil {
+			return fmt.Errorf("cryptutil: failed to write stream: %w", err)
+		}
+		if last {
+			return nil
+		}
+		if counter == math.MaxUint32 {
+			return errors.New("cryptutil: stream too long")
+		}
+	}
+}
+
+// DecryptStream decrypts a stream produced by EncryptStream from src and writes the plaintext to dst, in constant
+// memory. Chunks are authenticated before they are written, but if an error is returned dst may already hold the
+// plaintext of the chunks before the failing one, which must be discarded.
+func (a *AES256) DecryptStream(dst io.Writer, src io.Reader) error {
+	gcm, err := a.newGCM()
+	if err != nil {
+		return err
+	}
+
+	prefix := make([]byte, streamPrefixSize)
+	if _, err := io.ReadFull(src, prefix); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrTruncatedStream
+		}
+		return fmt.Errorf("cryptutil: failed to read stream: %w", err)
+	}
+
+	chunkSize := streamChunkSize + gcm.Overhead()
+	reader := bufio.NewReaderSize(src, chunkSize)
+	ciphertext := make([]byte, chunkSize)
+	plaintext := make([]byte, 0, streamChunkSize)
+	nonce := make([]byte, gcm.NonceSize())
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(reader, ciphertext)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("cryptutil: failed to read stream: %w", err)
+		}
+		if n < gcm.Overhead() {
+			return ErrTruncatedStream
+		}
+		last := n < chunkSize
+		if !last {
+			if _, err := reader.Peek(1); err == io.EOF {
+				last = true
+			}
+		}
+
+		plaintext, err = gcm.Open(plaintext[:0], streamNonce(nonce, prefix, counter, last), ciphertext[:n], nil)
+		if err != nil {
+			if last {
+				// The chunk may be a middle one whose successors were cut off
+				return fmt.Errorf("cryptutil: decryption failed, the stream is corrupted or truncated: %w", err)
+			}
+			return fmt.Errorf("cryptutil: decryption failed: %w", err)
+		}
+		if _, err := dst.Write(plaintext); err != nil {
+			return fmt.Errorf("cryptutil: failed to write stream: %w", err)
+		}
+		if last {
+			return nil
+		}
+		if counter == math.MaxUint32 {
+			return errors.New("cryptutil: stream too long")
+		}
+	}
+}
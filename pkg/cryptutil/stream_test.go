@@ -0,0 +1,62 @@
+package cryptutil_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/catalogfi/tools/pkg/cryptutil"
+	"github.com/stretchr/testify/require"
+)
+
+// testKeyHex is a fixed AES-256 key for the stream tests
+const testKeyHex = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+// TestStream verifies that streams of various sizes, including empty ones and exact chunk multiples, round trip.
+func TestStream(t *testing.T) {
+	aes, err := cryptutil.NewAES256(testKeyHex)
+	require.NoError(t, err)
+
+	for _, size := range []int{0, 1, 1000, 64 * 1024, 64*1024 + 1, 3*64*1024 + 17} {
+		plaintext := make([]byte, size)
+		_, err := rand.Read(plaintext)
+		require.NoError(t, err)
+
+		var encrypted bytes.Buffer
+		require.NoError(t, aes.EncryptStream(&encrypted, bytes.NewReader(plaintext)))
+
+		var decrypted bytes.Buffer
+		require.NoError(t, aes.DecryptStream(&decrypted, bytes.NewReader(encrypted.Bytes())))
+		require.Equal(t, size, decrypted.Len())
+		require.True(t, bytes.Equal(plaintext, decrypted.Bytes()), "size %d", size)
+	}
+}
+
+// TestStreamTampering verifies that truncated and modified streams are rejected.
+func TestStreamTampering(t *testing.T) {
+	aes, err := cryptutil.NewAES256(testKeyHex)
+	require.NoError(t, err)
+
+	plaintext := make([]byte, 2*64*1024+100)
+	var encrypted bytes.Buffer
+	require.NoError(t, aes.EncryptStream(&encrypted, bytes.NewReader(plaintext)))
+	data := encrypted.Bytes()
+
+	// Dropping the last chunk leaves a full chunk that wasn't sealed as the last one
+	chunk := 64*1024 + 16
+	err = aes.DecryptStream(&bytes.Buffer{}, bytes.NewReader(data[:7+2*chunk]))
+	require.Error(t, err)
+
+	err = aes.DecryptStream(&bytes.Buffer{}, bytes.NewReader(data[:3]))
+	require.ErrorIs(t, err, cryptutil.ErrTruncatedStream)
+
+	modified := bytes.Clone(data)
+	modified[len(modified)-1] ^= 1
+	err = aes.DecryptStream(&bytes.Buffer{}, bytes.NewReader(modified))
+	require.Error(t, err)
+
+	other, err := cryptutil.NewAES256("ffeeddccbbaa99887766554433221100ffeeddccbbaa99887766554433221100")
+	require.NoError(t, err)
+	err = other.DecryptStream(&bytes.Buffer{}, bytes.NewReader(data))
+	require.Error(t, err)
+}
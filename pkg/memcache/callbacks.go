@@ -0,0 +1,74 @@
+package memcache
+
+import "sync"
+
+// callbackQueue runs callbacks in order on its own goroutine. ristretto invokes its hooks on the goroutine that
+// applies Sets and inside Clear, so running user callbacks there would deadlock as soon as they call back into
+// the cache.
+type callbackQueue struct {
+	mu      sync.Mutex
+	pending []func()
+	notify  chan struct{}
+	done    chan struct{}
+	exited  chan struct{}
+}
+
+// newCallbackQueue starts the goroutine running the queued callbacks
+func newCallbackQueue() *callbackQueue {
+	q := &callbackQueue{
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// push queues fn without blocking
+func (q *callbackQueue) push(fn func()) {
+	q.mu.Lock()
+	q.pending = append(q.pending, fn)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run calls the queued callbacks until the queue is closed, then drains what is left
+func (q *callbackQueue) run() {
+	defer close(q.exited)
+	for {
+		select {
+		case <-q.notify:
+			q.drain()
+		case <-q.done:
+			q.drain()
+			return
+		}
+	}
+}
+
+// drain calls every callback queued so far
+func (q *callbackQueue) drain() {
+	for {
+		q.mu.Lock()
+		pending := q.pending
+		q.pending = nil
+		q.mu.Unlock()
+
+		if len(pending) == 0 {
+			return
+		}
+		for _, fn := range pending {
+			fn()
+		}
+	}
+}
+
+// close runs the remaining callbacks and stops the goroutine
+func (q *callbackQueue) close() {
+	close(q.done)
+	<-q.exited
+}
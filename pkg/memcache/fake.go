@@ -0,0 +1,372 @@
+package memcache
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var _ Cache[any] = (*Fake[any])(nil)
+
+// Fake is a Cache backed by a plain map, for unit tests of code that depends on a Cache. Unlike the ristretto
+// backed cache, it is immediately consistent: every Set is admitted and visible as soon as it returns, and entries
+// are only removed by Delete, Clear, Close or expiry. Expiry is checked against an injectable clock, so tests can
+// advance time instead of sleeping. The number of operations is recorded for assertions, see Counts.
+type Fake[V any] struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	opts    *options
+	entries map[string]fakeEntry[V]
+	counts  FakeCounts
+	group   singleflight.Group
+	closed  bool
+}
+
+// fakeEntry is a value of a Fake with its expiry, which is zero for entries that never expire
+type fakeEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+	ttl       time.Duration
+}
+
+// FakeCounts holds the number of operations a Fake has performed. Operations on several keys, such as GetMany, count
+// once per key.
+type FakeCounts struct {
+	// Gets counts reads of a key, each of which is either a hit or a miss
+	Gets   int
+	Hits   int
+	Misses int
+	// Sets counts the entries stored, including by GetOrSet and LoadSnapshot
+	Sets    int
+	Deletes int
+	Clears  int
+}
+
+// NewFake returns an empty Fake reading the time from now, or from time.Now when now is nil. Of the options, only
+// WithTtl, WithNoExpiry and WithSlidingTTL apply, the others are ignored.
+func NewFake[V any](now func() time.Time, opts ...Options) *Fake[V] {
+	if now == nil {
+		now = time.Now
+	}
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &Fake[V]{
+		now:     now,
+		opts:    o,
+		entries: make(map[string]fakeEntry[V]),
+	}
+}
+
+// Counts returns the number of operations performed so far
+func (f *Fake[V]) Counts() FakeCounts {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.counts
+}
+
+// get returns the entry of key if it hasn't expired, counting the read, the caller must hold mu
+func (f *Fake[V]) get(key string, refresh bool) (fakeEntry[V], bool) {
+	f.counts.Gets++
+	e, found := f.lookup(key)
+	if !found {
+		f.counts.Misses++
+		return e, false
+	}
+	f.counts.Hits++
+	if refresh && f.opts.slidingTTL {
+		e = f.entry(e.value, e.ttl)
+		f.entries[key] = e
+	}
+	return e, true
+}
+
+// lookup returns the entry of key if it hasn't expired without counting the read, the caller must hold mu
+func (f *Fake[V]) lookup(key string) (fakeEntry[V], bool) {
+	e, found := f.entries[key]
+	if !found {
+		return e, false
+	}
+	if !e.expiresAt.IsZero() && !f.now().Before(e.expiresAt) {
+		delete(f.entries, key)
+		return fakeEntry[V]{}, false
+	}
+	return e, true
+}
+
+// entry returns an entry expiring ttl from now, or never when ttl is zero
+func (f *Fake[V]) entry(value V, ttl time.Duration) fakeEntry[V] {
+	e := fakeEntry[V]{value: value, ttl: ttl}
+	if ttl > 0 {
+		e.expiresAt = f.now().Add(ttl)
+	}
+	return e
+}
+
+// set stores the entry, the caller must hold mu
+func (f *Fake[V]) set(key string, value V, ttl time.Duration) bool {
+	if f.closed {
+		return false
+	}
+	f.counts.Sets++
+	f.entries[key] = f.entry(value, ttl)
+	return true
+}
+
+// Get retrieves a value from the cache by key. It returns the value and a boolean indicating if the key was found.
+func (f *Fake[V]) Get(key string) (V, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, found := f.get(key, true)
+	return e.value, found
+}
+
+// Has reports whether key is in the cache, see Cache.
+func (f *Fake[V]) Has(key string) bool {
+	_, found := f.Get(key)
+	return found
+}
+
+// GetOrDefault returns the value of key, or def when key isn't in the cache.
+func (f *Fake[V]) GetOrDefault(key string, def V) V {
+	if value, found := f.Get(key); found {
+		return value
+	}
+	return def
+}
+
+// GetWithExpiry is like Get but also returns when the entry expires, see Cache.
+func (f *Fake[V]) GetWithExpiry(key string) (V, time.Time, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, found := f.get(key, false)
+	return e.value, e.expiresAt, found
+}
+
+// Set stores the value with the configured TTL.
+func (f *Fake[V]) Set(key string, value V) bool {
+	return f.SetWithTTL(key, value, f.opts.ttl)
+}
+
+// SetWithTTL stores the value with its own TTL. A zero ttl means the entry never expires.
+func (f *Fake[V]) SetWithTTL(key string, value V, ttl time.Duration) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.set(key, value, ttl)
+}
+
+// GetCtx is like Get but misses when ctx is already done.
+func (f *Fake[V]) GetCtx(ctx context.Context, key string) (V, bool) {
+	if ctx.Err() != nil {
+		var zero V
+		return zero, false
+	}
+	return f.Get(key)
+}
+
+// SetCtx is like Set but stores nothing when ctx is already done.
+func (f *Fake[V]) SetCtx(ctx context.Context, key string, value V) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return f.Set(key, value)
+}
+
+// SetWithCost is like Set, costs are ignored since the Fake has no size limit.
+func (f *Fake[V]) SetWithCost(key string, value V, _ int64) bool {
+	return f.Set(key, value)
+}
+
+// SetIfAbsent stores the value only when key isn't in the cache and reports whether it did.
+func (f *Fake[V]) SetIfAbsent(key string, value V) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, found := f.lookup(key); found {
+		return false
+	}
+	return f.set(key, value, f.opts.ttl)
+}
+
+// Touch sets the TTL of an existing entry to ttl from now, see Cache.
+func (f *Fake[V]) Touch(key string, ttl time.Duration) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, found := f.lookup(key)
+	if !found {
+		return false
+	}
+	f.entries[key] = f.entry(e.value, ttl)
+	return true
+}
+
+// GetMany retrieves several values at once. The returned map only contains the keys that were found.
+func (f *Fake[V]) GetMany(keys []string) map[string]V {
+	values := make(map[string]V, len(keys))
+	for _, key := range keys {
+		if value, found := f.Get(key); found {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// SetMany stores several values at once with the configured TTL.
+func (f *Fake[V]) SetMany(entries map[string]V) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for key, value := range entries {
+		f.set(key, value, f.opts.ttl)
+	}
+}
+
+// Preload stores several values at once with the configured TTL and returns how many were stored, which is all of
+// them unless the Fake is closed.
+func (f *Fake[V]) Preload(entries map[string]V) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	admitted := 0
+	for key, value := range entries {
+		if f.set(key, value, f.opts.ttl) {
+			admitted++
+		}
+	}
+	return admitted
+}
+
+// Delete removes a key from the cache.
+func (f *Fake[V]) Delete(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.counts.Deletes++
+	delete(f.entries, key)
+}
+
+// DeleteMany removes several keys at once.
+func (f *Fake[V]) DeleteMany(keys []string) {
+	for _, key := range keys {
+		f.Delete(key)
+	}
+}
+
+// GetOrSet returns the cached value for key on a hit. On a miss it calls loader, stores the result and returns it.
+// Errors returned by loader are passed through and nothing is cached.
+func (f *Fake[V]) GetOrSet(key string, loader func() (V, error)) (V, error) {
+	if value, found := f.Get(key); found {
+		return value, nil
+	}
+
+	value, err := loader()
+	if err != nil {
+		return value, err
+	}
+	f.Set(key, value)
+	return value, nil
+}
+
+// GetOrSetSingleflight is like GetOrSet but concurrent misses for the same key share a single call to loader.
+func (f *Fake[V]) GetOrSetSingleflight(key string, loader func() (V, error)) (V, error) {
+	if value, found := f.Get(key); found {
+		return value, nil
+	}
+
+	result, err, _ := f.group.Do(key, func() (any, error) {
+		return f.GetOrSet(key, loader)
+	})
+	// A nil result, such as a failed load of an interface type, doesn't assert to V
+	value, _ := result.(V)
+	return value, err
+}
+
+// Clear removes every entry from the cache.
+func (f *Fake[V]) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.counts.Clears++
+	f.entries = make(map[string]fakeEntry[V])
+}
+
+// Metrics returns the operation counts in the form of the cache statistics. Cost is the number of entries, expired
+// or not, and KeysEvicted is always zero.
+func (f *Fake[V]) Metrics() Metrics {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	m := Metrics{
+		Enabled:   true,
+		Hits:      uint64(f.counts.Hits),
+		Misses:    uint64(f.counts.Misses),
+		KeysAdded: uint64(f.counts.Sets),
+		Cost:      uint64(len(f.entries)),
+	}
+	if f.counts.Gets > 0 {
+		m.Ratio = float64(f.counts.Hits) / float64(f.counts.Gets)
+	}
+	return m
+}
+
+// Keys returns the keys that haven't expired, in no particular order.
+func (f *Fake[V]) Keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.entries))
+	for key := range f.entries {
+		if _, found := f.lookup(key); found {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Close drops every entry. Afterwards Get always misses and Set returns false.
+func (f *Fake[V]) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	f.entries = make(map[string]fakeEntry[V])
+}
+
+// SaveSnapshot writes every entry that hasn't expired to w, in the format of the ristretto backed cache.
+func (f *Fake[V]) SaveSnapshot(w io.Writer) error {
+	f.mu.Lock()
+	entries := make([]snapshotEntry[string, V], 0, len(f.entries))
+	for key := range f.entries {
+		if e, found := f.lookup(key); found {
+			entries = append(entries, snapshotEntry[string, V]{Key: key, Value: e.value, ExpiresAt: e.expiresAt})
+		}
+	}
+	f.mu.Unlock()
+
+	if err := gob.NewEncoder(w).Encode(entries); err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot adds the entries of a snapshot, skipping the ones that have expired according to the clock.
+func (f *Fake[V]) LoadSnapshot(r io.Reader) error {
+	var entries []snapshotEntry[string, V]
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	for _, e := range entries {
+		var ttl time.Duration
+		if !e.ExpiresAt.IsZero() {
+			ttl = e.ExpiresAt.Sub(now)
+			if ttl <= 0 {
+				continue
+			}
+		}
+		f.set(e.Key, e.Value, ttl)
+	}
+	return nil
+}
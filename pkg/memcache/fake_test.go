@@ -0,0 +1,129 @@
+package memcache_test
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/catalogfi/tools/pkg/memcache"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fake", func() {
+	var cache *memcache.Fake[string]
+	var now time.Time
+
+	BeforeEach(func() {
+		now = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		cache = memcache.NewFake[string](func() time.Time { return now }, memcache.WithTtl(time.Minute))
+	})
+
+	It("should satisfy the Cache interface", func() {
+		var c memcache.Cache[string] = cache
+		Expect(c.Set("foo", "bar")).To(BeTrue())
+		Expect(c.GetOrDefault("foo", "")).To(Equal("bar"))
+	})
+
+	It("should be immediately consistent and count operations", func() {
+		Expect(cache.Set("foo", "bar")).To(BeTrue())
+		value, found := cache.Get("foo")
+		Expect(found).To(BeTrue())
+		Expect(value).To(Equal("bar"))
+
+		_, found = cache.Get("missing")
+		Expect(found).To(BeFalse())
+
+		Expect(cache.Keys()).To(ConsistOf("foo"))
+		cache.Delete("foo")
+		Expect(cache.Has("foo")).To(BeFalse())
+		Expect(cache.Keys()).To(BeEmpty())
+
+		Expect(cache.Counts()).To(Equal(memcache.FakeCounts{Gets: 3, Hits: 1, Misses: 2, Sets: 1, Deletes: 1}))
+		Expect(cache.Metrics().Ratio).To(BeNumerically("~", 1.0/3))
+	})
+
+	It("should expire entries according to the clock", func() {
+		Expect(cache.Set("foo", "bar")).To(BeTrue())
+		Expect(cache.SetWithTTL("forever", "bar", 0)).To(BeTrue())
+
+		_, expiresAt, found := cache.GetWithExpiry("foo")
+		Expect(found).To(BeTrue())
+		Expect(expiresAt).To(Equal(now.Add(time.Minute)))
+
+		now = now.Add(59 * time.Second)
+		Expect(cache.Has("foo")).To(BeTrue())
+		now = now.Add(time.Second)
+		Expect(cache.Has("foo")).To(BeFalse())
+		Expect(cache.Has("forever")).To(BeTrue())
+
+		Expect(cache.SetWithTTL("short", "bar", time.Second)).To(BeTrue())
+		Expect(cache.Touch("short", time.Hour)).To(BeTrue())
+		now = now.Add(time.Minute)
+		Expect(cache.Has("short")).To(BeTrue())
+	})
+
+	It("should extend the lifetime of entries read with a sliding TTL", func() {
+		cache = memcache.NewFake[string](func() time.Time { return now }, memcache.WithTtl(time.Minute), memcache.WithSlidingTTL())
+		Expect(cache.Set("foo", "bar")).To(BeTrue())
+
+		for range 3 {
+			now = now.Add(40 * time.Second)
+			Expect(cache.Has("foo")).To(BeTrue())
+		}
+		now = now.Add(time.Minute)
+		Expect(cache.Has("foo")).To(BeFalse())
+	})
+
+	It("should renew entries read with a sliding TTL with the TTL they were set with", func() {
+		cache = memcache.NewFake[string](func() time.Time { return now }, memcache.WithNoExpiry(), memcache.WithSlidingTTL())
+		Expect(cache.SetWithTTL("foo", "bar", time.Second)).To(BeTrue())
+
+		now = now.Add(800 * time.Millisecond)
+		Expect(cache.Has("foo")).To(BeTrue())
+		now = now.Add(800 * time.Millisecond)
+		Expect(cache.Has("foo")).To(BeTrue())
+		now = now.Add(time.Second)
+		Expect(cache.Has("foo")).To(BeFalse())
+	})
+
+	It("should round trip snapshots and skip expired entries", func() {
+		Expect(cache.Set("foo", "bar")).To(BeTrue())
+		Expect(cache.SetWithTTL("short", "baz", time.Second)).To(BeTrue())
+
+		var buf bytes.Buffer
+		Expect(cache.SaveSnapshot(&buf)).To(Succeed())
+
+		now = now.Add(2 * time.Second)
+		restored := memcache.NewFake[string](func() time.Time { return now })
+		Expect(restored.LoadSnapshot(&buf)).To(Succeed())
+		Expect(restored.GetMany([]string{"foo", "short"})).To(Equal(map[string]string{"foo": "bar"}))
+		_, expiresAt, _ := restored.GetWithExpiry("foo")
+		Expect(expiresAt).To(Equal(now.Add(58 * time.Second)))
+	})
+
+	It("should stop storing once closed", func() {
+		Expect(cache.Set("foo", "bar")).To(BeTrue())
+		cache.Close()
+		Expect(cache.Has("foo")).To(BeFalse())
+		Expect(cache.Set("foo", "bar")).To(BeFalse())
+		Expect(cache.Preload(map[string]string{"foo": "bar"})).To(BeZero())
+	})
+
+	It("should return the loader error for an interface value type", func() {
+		fake := memcache.NewFake[error](nil)
+		value, err := fake.GetOrSetSingleflight("foo", func() (error, error) {
+			return nil, errors.New("backend unavailable")
+		})
+		Expect(err).Should(MatchError("backend unavailable"))
+		Expect(value).To(BeNil())
+		Expect(fake.Has("foo")).To(BeFalse())
+	})
+
+	It("should preload every entry", func() {
+		Expect(cache.Preload(map[string]string{"foo": "1", "bar": "2"})).To(Equal(2))
+		Expect(cache.GetMany([]string{"foo", "bar"})).To(Equal(map[string]string{"foo": "1", "bar": "2"}))
+		Expect(cache.Counts().Sets).To(Equal(2))
+	})
+})
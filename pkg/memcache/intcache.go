@@ -0,0 +1,52 @@
+package memcache
+
+import (
+	"sync"
+	"time"
+)
+
+// IntCache is a cache of int64 counters, such as the ones of a rate limiter, that can be incremented atomically
+type IntCache[K comparable] struct {
+	KeyedCache[K, int64]
+
+	// mu serializes increments so that concurrent ones don't lose updates
+	mu sync.Mutex
+}
+
+// NewIntCache creates a new counter cache, see NewKeyed. WithAsyncSet is ignored since an increment must see the
+// previous one.
+func NewIntCache[K comparable](opts ...Options) (*IntCache[K], error) {
+	opts = append(opts, func(opts *options) {
+		opts.asyncSet = false
+	})
+	cache, err := NewKeyed[K, int64](opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &IntCache[K]{KeyedCache: cache}, nil
+}
+
+// Increment adds delta to the counter of key, starting from zero when it is absent, and returns the new value.
+// A counter keeps the expiry it was created with, so a TTL bounds the window it counts over. The boolean is false
+// when the cache didn't admit the new value.
+//
+// Increments are atomic with respect to each other, but not to Set or Delete on the same key. Because ristretto
+// may reject or evict any entry, a counter can also restart from zero; treat the value as a close lower bound
+// rather than an exact count.
+func (c *IntCache[K]) Increment(key K, delta int64) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value, expiresAt, found := c.GetWithExpiry(key)
+	value += delta
+	if !found || expiresAt.IsZero() {
+		return value, c.Set(key, value)
+	}
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// The counter expired in the meantime, start a new one
+		return delta, c.Set(key, delta)
+	}
+	return value, c.SetWithTTL(key, value, ttl)
+}
@@ -1,9 +1,17 @@
 package memcache
 
 import (
+	"context"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"math/rand/v2"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/dgraph-io/ristretto/v2"
+	"golang.org/x/sync/singleflight"
 )
 
 // Options is a functional option type for configuring memCache
@@ -16,6 +24,17 @@ type options struct {
 	metrics                bool
 	ttl                    time.Duration
 	ttlTickerDurationInSec int64
+	costFunc               any
+	slidingTTL             bool
+	onEvict                any
+	asyncSet               bool
+	ttlJitter              float64
+	ignoreInternalCost     bool
+	onReject               any
+	setRetries             int
+	keyIndex               bool
+	metricsInterval        time.Duration
+	metricsLog             func(Metrics)
 }
 
 // defaultOptions returns the default options for memCache
@@ -38,7 +57,8 @@ func WithNumCounters(numCounters int64) Options {
 }
 
 // See https://github.com/hypermodeinc/ristretto/blob/main/cache.go#L97-L112
-// We assume all entry are of cost 1 to simplify things.
+// Entries cost 1 unless a cost is given to SetWithCost or WithCostFunc is set, so by default maxCost is roughly the
+// number of entries, plus the internal cost ristretto adds to each of them (see WithIgnoreInternalCost).
 func WithMaxCost(maxCost int64) Options {
 	return func(opts *options) {
 		opts.maxCost = maxCost
@@ -52,13 +72,31 @@ func WithMetrics(metrics bool) Options {
 	}
 }
 
+// See https://github.com/hypermodeinc/ristretto/blob/main/cache.go#L175-L179
+// WithIgnoreInternalCost stops ristretto from adding its own per-entry overhead to the cost of each entry, so that
+// maxCost is measured exactly in the costs given to SetWithCost or returned by the cost function.
+func WithIgnoreInternalCost(ignoreInternalCost bool) Options {
+	return func(opts *options) {
+		opts.ignoreInternalCost = ignoreInternalCost
+	}
+}
+
 // WithTtl sets the expiry time for each value entry of the cache.
+// Without it, or with a zero ttl, entries never expire and only leave the cache when evicted to respect maxCost.
 func WithTtl(ttl time.Duration) Options {
 	return func(opts *options) {
 		opts.ttl = ttl
 	}
 }
 
+// WithNoExpiry makes entries never expire unless set with their own TTL, overriding any WithTtl before it. This is
+// also the default, the option only makes the intent explicit.
+func WithNoExpiry() Options {
+	return func(opts *options) {
+		opts.ttl = 0
+	}
+}
+
 // See https://github.com/hypermodeinc/ristretto/blob/main/cache.go#L181
 // TtlTickerDurationInSec sets the value of time ticker for cleanup keys on TTL expiry.
 func WithTtlTickerDurationInSec(ttlTickerDurationInSec int64) Options {
@@ -67,47 +105,617 @@ func WithTtlTickerDurationInSec(ttlTickerDurationInSec int64) Options {
 	}
 }
 
-// Cache is a generic interface for caching operations
-type Cache[V any] interface {
-	Get(key string) (V, bool)
-	Set(key string, value V) bool
+// WithSlidingTTL makes every successful Get extend the lifetime of the entry by the TTL it was stored with, the
+// configured one or the one given to SetWithTTL or Touch, so entries only expire once they haven't been read for
+// that long. The refresh is buffered without waiting for it to be applied, so a Set racing with a Get of the same
+// key may be overwritten by the value that Get returned.
+func WithSlidingTTL() Options {
+	return func(opts *options) {
+		opts.slidingTTL = true
+	}
+}
+
+// WithAsyncSet makes Set, SetWithTTL, SetWithCost and SetMany return as soon as the entry is buffered instead of
+// waiting for it to be applied. This raises write throughput, but a Get right after a Set may miss the new value
+// or still return the old one until ristretto processes its buffer.
+func WithAsyncSet() Options {
+	return func(opts *options) {
+		opts.asyncSet = true
+	}
+}
+
+// WithTTLJitter randomizes the TTL of every entry set with an expiry by up to +/- fraction of it, so entries set
+// together don't all expire at once. A fraction of 0.1 turns a TTL of 10 minutes into one between 9 and 11 minutes.
+// The jittered TTL never drops to zero or below, which would mean no expiry.
+func WithTTLJitter(fraction float64) Options {
+	return func(opts *options) {
+		opts.ttlJitter = fraction
+	}
+}
+
+// WithSetRetries makes Set, SetWithTTL and SetWithCost check that the entry was admitted and store it again up to
+// setRetries times when it wasn't. Each attempt waits for ristretto to apply the entry, and reads it back, which
+// also raises its standing with the admission policy. This improves the odds of admission under pressure but
+// doesn't guarantee it. It has no effect with WithAsyncSet.
+func WithSetRetries(setRetries int) Options {
+	return func(opts *options) {
+		opts.setRetries = setRetries
+	}
+}
+
+// WithCostFunc sets the function used to compute the cost of each entry added with Set or SetWithTTL, for example
+// its size in bytes, instead of the default cost of 1. The value type must match the one the cache is created with.
+func WithCostFunc[V any](costFunc func(V) int64) Options {
+	return func(opts *options) {
+		opts.costFunc = costFunc
+	}
+}
+
+// WithOnEvict sets a function called with the key and value of every entry evicted by the cache, whether to make
+// room for new entries, because it expired or because the cache was cleared or closed. Deleted entries aren't
+// reported. The function runs on a separate goroutine, in eviction order, so it may safely call back into the
+// cache. The key and value types must match the ones the cache is created with.
+func WithOnEvict[K comparable, V any](onEvict func(key K, value V)) Options {
+	return func(opts *options) {
+		opts.onEvict = onEvict
+	}
+}
+
+// WithOnReject sets a function called with the key and value of every entry the cache didn't admit, either because
+// ristretto's admission policy rejected it or because its write buffer was full. Like the eviction function, it
+// runs on a separate goroutine and may safely call back into the cache. The key and value types must match the
+// ones the cache is created with.
+func WithOnReject[K comparable, V any](onReject func(key K, value V)) Options {
+	return func(opts *options) {
+		opts.onReject = onReject
+	}
+}
+
+// WithKeyIndex maintains a set of the keys in the cache alongside ristretto, which can't enumerate its entries. The
+// index is updated on every set, delete and eviction, and is required by Keys and SaveSnapshot. It costs a map
+// entry per key, roughly the size of the key plus a few dozen bytes, on top of the memory ristretto uses, and a
+// mutex acquisition on every write.
+func WithKeyIndex() Options {
+	return func(opts *options) {
+		opts.keyIndex = true
+	}
+}
+
+// WithMetricsLogger calls log with a snapshot of the cache metrics every interval, for example to report the hit
+// ratio and size of the cache. The calls run on a goroutine of their own, stopped by Close. Nothing is started
+// unless metrics are enabled with WithMetrics(true), or when interval isn't positive.
+func WithMetricsLogger(interval time.Duration, log func(Metrics)) Options {
+	return func(opts *options) {
+		opts.metricsInterval = interval
+		opts.metricsLog = log
+	}
+}
+
+// KeyedCache is a generic interface for caching operations on keys of any comparable type
+type KeyedCache[K comparable, V any] interface {
+	Get(key K) (V, bool)
+	Has(key K) bool
+	GetOrDefault(key K, def V) V
+	GetWithExpiry(key K) (V, time.Time, bool)
+	Set(key K, value V) bool
+	SetWithTTL(key K, value V, ttl time.Duration) bool
+	GetCtx(ctx context.Context, key K) (V, bool)
+	SetCtx(ctx context.Context, key K, value V) bool
+	SetWithCost(key K, value V, cost int64) bool
+	SetIfAbsent(key K, value V) bool
+	Touch(key K, ttl time.Duration) bool
+	GetMany(keys []K) map[K]V
+	SetMany(entries map[K]V)
+	Preload(entries map[K]V) int
+	Delete(key K)
+	DeleteMany(keys []K)
+	GetOrSet(key K, loader func() (V, error)) (V, error)
+	GetOrSetSingleflight(key K, loader func() (V, error)) (V, error)
+	Clear()
+	Metrics() Metrics
+	Keys() []K
+	Close()
+	SaveSnapshot(w io.Writer) error
+	LoadSnapshot(r io.Reader) error
+}
+
+// Cache is a KeyedCache with string keys
+type Cache[V any] = KeyedCache[string, V]
+
+// Metrics is a snapshot of the cache statistics. All counters are zero and Enabled is false unless the cache
+// was created with WithMetrics(true).
+type Metrics struct {
+	Enabled     bool
+	Hits        uint64
+	Misses      uint64
+	KeysAdded   uint64
+	KeysEvicted uint64
+	// Cost is the total cost of the entries currently in the cache, including ristretto's internal per-entry cost
+	// unless WithIgnoreInternalCost is set
+	Cost uint64
+	// Ratio is the fraction of Gets that were hits
+	Ratio float64
+}
+
+// entry is the value stored in ristretto, which is keyed by the hash of the key and only hands that hash to its
+// callbacks. ttl is the lifetime the entry was stored with, which a sliding TTL renews.
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+	ttl   time.Duration
 }
 
 // memCache is a generic wrapper around ristretto.Cache
-type memCache[V any] struct {
-	cache *ristretto.Cache[string, V]
-	opts  *options
+type memCache[K comparable, V any] struct {
+	// mu guards cache against Clear and Close, which ristretto doesn't allow concurrently with other operations
+	mu        sync.RWMutex
+	cache     *ristretto.Cache[uint64, entry[K, V]]
+	seed      maphash.Seed
+	opts      *options
+	costFunc  func(V) int64
+	onEvict   func(key K, value V)
+	onReject  func(key K, value V)
+	callbacks *callbackQueue
+	keys      *keyIndex[K]
+	logger    *metricsLogger
+	group     singleflight.Group
+	closed    bool
+
+	// absentMu serializes SetIfAbsent so that only one of several concurrent calls for a key wins
+	absentMu sync.Mutex
 }
 
-// New creates a new memory cache with the specified TTL
+// optionFunc returns the function stored in an option, checking that it matches the types of the cache
+func optionFunc[F any](name string, fn any) (F, error) {
+	var zero F
+	if fn == nil {
+		return zero, nil
+	}
+	typed, ok := fn.(F)
+	if !ok {
+		return zero, fmt.Errorf("%s %T doesn't match the key and value types of the cache", name, fn)
+	}
+	return typed, nil
+}
+
+// New creates a new memory cache with the specified TTL, entries never expire when no TTL is configured. Close must be called once the cache is no longer needed
+// to stop its background goroutines.
 func New[V any](opts ...Options) (Cache[V], error) {
+	return NewKeyed[string, V](opts...)
+}
+
+// NewKeyed is like New but creates a cache whose keys are of any comparable type, such as typed IDs or structs.
+// Keys are hashed to 64 bits, and a hash collision makes the colliding keys evict each other rather than return
+// the wrong value.
+func NewKeyed[K comparable, V any](opts ...Options) (KeyedCache[K, V], error) {
 	defaultOpts := defaultOptions()
 	for _, opt := range opts {
 		opt(defaultOpts)
 	}
 
-	c, err := ristretto.NewCache(&ristretto.Config[string, V]{
+	costFunc, err := optionFunc[func(V) int64]("cost function", defaultOpts.costFunc)
+	if err != nil {
+		return nil, err
+	}
+	onEvict, err := optionFunc[func(K, V)]("eviction function", defaultOpts.onEvict)
+	if err != nil {
+		return nil, err
+	}
+
+	onReject, err := optionFunc[func(K, V)]("rejection function", defaultOpts.onReject)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &memCache[K, V]{
+		seed:     maphash.MakeSeed(),
+		opts:     defaultOpts,
+		costFunc: costFunc,
+		onEvict:  onEvict,
+		onReject: onReject,
+	}
+	if defaultOpts.keyIndex {
+		cache.keys = newKeyIndex[K]()
+	}
+	config := &ristretto.Config[uint64, entry[K, V]]{
 		NumCounters:            defaultOpts.numCounters,
 		MaxCost:                defaultOpts.maxCost,
 		BufferItems:            64,
 		Metrics:                defaultOpts.metrics,
 		TtlTickerDurationInSec: defaultOpts.ttlTickerDurationInSec,
-	})
+		IgnoreInternalCost:     defaultOpts.ignoreInternalCost,
+		OnEvict:                cache.evicted,
+		OnReject:               cache.rejected,
+	}
+
+	c, err := ristretto.NewCache(config)
 	if err != nil {
 		return nil, err
 	}
+	if onEvict != nil || onReject != nil {
+		cache.callbacks = newCallbackQueue()
+	}
+	cache.cache = c
+	if defaultOpts.metrics && defaultOpts.metricsLog != nil && defaultOpts.metricsInterval > 0 {
+		cache.logger = newMetricsLogger(defaultOpts.metricsInterval, cache.Metrics, defaultOpts.metricsLog)
+	}
+	return cache, nil
+}
+
+// evicted drops the evicted item from the key index and queues the eviction callback
+func (cache *memCache[K, V]) evicted(item *ristretto.Item[entry[K, V]]) {
+	e := item.Value
+	cache.keys.remove(e.key)
+	if cache.onEvict != nil {
+		cache.callbacks.push(func() { cache.onEvict(e.key, e.value) })
+	}
+}
 
-	return &memCache[V]{cache: c, opts: defaultOpts}, nil
+// rejected drops the item that wasn't admitted from the key index and queues the rejection callback
+func (cache *memCache[K, V]) rejected(item *ristretto.Item[entry[K, V]]) {
+	cache.keys.remove(item.Value.key)
+	cache.notifyRejected(item.Value.key, item.Value.value)
+}
+
+// notifyRejected queues the rejection callback for the entry
+func (cache *memCache[K, V]) notifyRejected(key K, value V) {
+	if cache.onReject != nil {
+		cache.callbacks.push(func() { cache.onReject(key, value) })
+	}
 }
 
 // Get retrieves a value from the cache by key. It returns the value and a boolean indicating if the key was found.
-func (cache *memCache[V]) Get(key string) (V, bool) {
-	return cache.cache.Get(key)
+func (cache *memCache[K, V]) Get(key K) (V, bool) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	hash := cache.hash(key)
+	e, found := cache.cache.Get(hash)
+	if !found || e.key != key {
+		var zero V
+		return zero, false
+	}
+	if cache.opts.slidingTTL {
+		// Refresh the expiry without waiting, Get must stay non-blocking
+		cache.cache.SetWithTTL(hash, e, cache.cost(e.value), e.ttl)
+	}
+	return e.value, true
+}
+
+// Has reports whether key is in the cache without returning its value. With WithSlidingTTL, it extends the
+// lifetime of the entry like Get does.
+func (cache *memCache[K, V]) Has(key K) bool {
+	_, found := cache.Get(key)
+	return found
+}
+
+// GetOrDefault returns the value of key, or def when key isn't in the cache.
+func (cache *memCache[K, V]) GetOrDefault(key K, def V) V {
+	if value, found := cache.Get(key); found {
+		return value
+	}
+	return def
+}
+
+// GetWithExpiry is like Get but also returns when the entry expires. The time is zero for entries that never
+// expire. It doesn't extend the lifetime of the entry, even with WithSlidingTTL.
+func (cache *memCache[K, V]) GetWithExpiry(key K) (V, time.Time, bool) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	return cache.lookup(key)
+}
+
+// lookup returns the value of key and its expiry without refreshing it, the caller must hold mu
+func (cache *memCache[K, V]) lookup(key K) (V, time.Time, bool) {
+	hash := cache.hash(key)
+	e, found := cache.cache.Get(hash)
+	if !found || e.key != key {
+		var zero V
+		return zero, time.Time{}, false
+	}
+	var expiresAt time.Time
+	if ttl, ok := cache.cache.GetTTL(hash); ok && ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+	return e.value, expiresAt, true
+}
+
+// hash returns the key ristretto stores the entry for key under
+func (cache *memCache[K, V]) hash(key K) uint64 {
+	return maphash.Comparable(cache.seed, key)
 }
 
 // Set adds a value to the cache with a specified key. It returns true if the value was successfully set, false otherwise.
-func (cache *memCache[V]) Set(key string, value V) bool {
-	result := cache.cache.SetWithTTL(key, value, 1, cache.opts.ttl)
+func (cache *memCache[K, V]) Set(key K, value V) bool {
+	return cache.SetWithTTL(key, value, cache.opts.ttl)
+}
+
+// SetWithTTL is like Set but overrides the configured TTL for this entry. A zero ttl means the entry never expires.
+func (cache *memCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) bool {
+	return cache.set(key, value, cache.cost(value), ttl)
+}
+
+// SetWithCost is like Set but uses the given cost for this entry instead of the cost function or the default of 1.
+func (cache *memCache[K, V]) SetWithCost(key K, value V, cost int64) bool {
+	return cache.set(key, value, cost, cache.opts.ttl)
+}
+
+// SetIfAbsent stores the value only when key isn't in the cache and reports whether it did. Concurrent calls to
+// SetIfAbsent are serialized so only the first one stores, but a plain Set racing with it may still be overwritten
+// or overwrite it. It also returns false when ristretto doesn't admit the value, and with WithAsyncSet a value set
+// just before may not be visible yet, in which case both calls store.
+func (cache *memCache[K, V]) SetIfAbsent(key K, value V) bool {
+	cache.absentMu.Lock()
+	defer cache.absentMu.Unlock()
+
+	cache.mu.RLock()
+	_, _, found := cache.lookup(key)
+	cache.mu.RUnlock()
+	if found {
+		return false
+	}
+	return cache.Set(key, value)
+}
+
+// Touch sets the TTL of an existing entry to ttl from now without changing its value, and returns false when key
+// isn't in the cache. A zero ttl removes the expiry. The entry is stored again, so its cost is recomputed with the
+// cost function or reset to 1.
+func (cache *memCache[K, V]) Touch(key K, ttl time.Duration) bool {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	value, _, found := cache.lookup(key)
+	if !found {
+		return false
+	}
+	result := cache.store(key, value, cache.cost(value), ttl)
 	cache.cache.Wait()
 	return result
 }
+
+// cost returns the cost of value according to the cost function, or 1 when there is none
+func (cache *memCache[K, V]) cost(value V) int64 {
+	if cache.costFunc == nil {
+		return 1
+	}
+	return cache.costFunc(value)
+}
+
+// jitter randomizes ttl according to WithTTLJitter, keeping it positive. A zero ttl means no expiry and is kept.
+func (cache *memCache[K, V]) jitter(ttl time.Duration) time.Duration {
+	if ttl <= 0 || cache.opts.ttlJitter <= 0 {
+		return ttl
+	}
+	jittered := time.Duration(float64(ttl) * (1 + cache.opts.ttlJitter*(2*rand.Float64()-1)))
+	if jittered <= 0 {
+		return time.Nanosecond
+	}
+	return jittered
+}
+
+// set adds the entry and waits for it to be applied unless sets are asynchronous, retrying as configured
+func (cache *memCache[K, V]) set(key K, value V, cost int64, ttl time.Duration) bool {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	if cache.opts.asyncSet {
+		return cache.store(key, value, cost, cache.jitter(ttl))
+	}
+
+	for attempt := 0; ; attempt++ {
+		result := cache.store(key, value, cost, cache.jitter(ttl))
+		cache.cache.Wait()
+		if cache.opts.setRetries <= 0 {
+			return result
+		}
+		if result {
+			if _, _, found := cache.lookup(key); found {
+				return true
+			}
+		}
+		if attempt == cache.opts.setRetries || cache.closed {
+			return false
+		}
+	}
+}
+
+// GetCtx is like Get but misses without reading the cache when ctx is already done. ristretto is purely in
+// memory, so there is nothing to cancel once the read has started.
+func (cache *memCache[K, V]) GetCtx(ctx context.Context, key K) (V, bool) {
+	if ctx.Err() != nil {
+		var zero V
+		return zero, false
+	}
+	return cache.Get(key)
+}
+
+// SetCtx is like Set but returns false without storing anything when ctx is already done, and stops waiting for
+// the entry to be applied when ctx is done in the meantime. In that case the entry may still be stored shortly
+// after SetCtx returns false.
+func (cache *memCache[K, V]) SetCtx(ctx context.Context, key K, value V) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	cache.mu.RLock()
+	if !cache.store(key, value, cache.cost(value), cache.jitter(cache.opts.ttl)) {
+		cache.mu.RUnlock()
+		return false
+	}
+	applied := make(chan struct{})
+	go func() {
+		defer cache.mu.RUnlock()
+		defer close(applied)
+		cache.cache.Wait()
+	}()
+
+	select {
+	case <-applied:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// GetMany retrieves several values at once. The returned map only contains the keys that were found.
+func (cache *memCache[K, V]) GetMany(keys []K) map[K]V {
+	values := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, found := cache.Get(key); found {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// SetMany adds several values at once with the configured TTL. Unlike calling Set for each entry, it waits only
+// once after buffering all of them, so an entry may not be readable until SetMany returns rather than right after
+// it is buffered. It doesn't report entries rejected by the cache.
+func (cache *memCache[K, V]) SetMany(entries map[K]V) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	for key, value := range entries {
+		cache.store(key, value, cache.cost(value), cache.jitter(cache.opts.ttl))
+	}
+	if !cache.opts.asyncSet {
+		cache.cache.Wait()
+	}
+}
+
+// Preload adds several values at once with the configured TTL, for example to warm the cache up on startup, and
+// returns how many of them were admitted. Like SetMany it waits only once after buffering all of them, even with
+// WithAsyncSet, so every admitted entry is readable when Preload returns. Checking admission doesn't count as a
+// read in the metrics or the admission policy.
+func (cache *memCache[K, V]) Preload(entries map[K]V) int {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	for key, value := range entries {
+		cache.store(key, value, cache.cost(value), cache.jitter(cache.opts.ttl))
+	}
+	cache.cache.Wait()
+
+	admitted := 0
+	for key := range entries {
+		if _, found := cache.cache.GetTTL(cache.hash(key)); found {
+			admitted++
+		}
+	}
+	return admitted
+}
+
+// store buffers the entry in ristretto and records its key, the caller must hold mu and wait if needed
+func (cache *memCache[K, V]) store(key K, value V, cost int64, ttl time.Duration) bool {
+	cache.keys.add(key)
+	if !cache.cache.SetWithTTL(cache.hash(key), entry[K, V]{key: key, value: value, ttl: ttl}, cost, ttl) {
+		if !cache.closed {
+			cache.notifyRejected(key, value)
+		}
+		return false
+	}
+	return true
+}
+
+// Delete removes a key from the cache. Like Set, it waits for the operation to be applied so a subsequent Get misses.
+func (cache *memCache[K, V]) Delete(key K) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	cache.cache.Del(cache.hash(key))
+	cache.keys.remove(key)
+	cache.cache.Wait()
+}
+
+// DeleteMany removes several keys at once, waiting only once after all of them are removed.
+func (cache *memCache[K, V]) DeleteMany(keys []K) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	for _, key := range keys {
+		cache.cache.Del(cache.hash(key))
+		cache.keys.remove(key)
+	}
+	cache.cache.Wait()
+}
+
+// GetOrSet returns the cached value for key on a hit. On a miss it calls loader, stores the result and returns it.
+// Errors returned by loader are passed through and nothing is cached.
+func (cache *memCache[K, V]) GetOrSet(key K, loader func() (V, error)) (V, error) {
+	if value, found := cache.Get(key); found {
+		return value, nil
+	}
+
+	value, err := loader()
+	if err != nil {
+		return value, err
+	}
+	cache.Set(key, value)
+	return value, nil
+}
+
+// GetOrSetSingleflight is like GetOrSet but concurrent misses for the same key share a single call to loader,
+// and every caller receives its result.
+func (cache *memCache[K, V]) GetOrSetSingleflight(key K, loader func() (V, error)) (V, error) {
+	if value, found := cache.Get(key); found {
+		return value, nil
+	}
+
+	result, err, _ := cache.group.Do(strconv.FormatUint(cache.hash(key), 16), func() (any, error) {
+		// Another flight may have stored the value between the miss above and this call
+		if value, found := cache.Get(key); found {
+			return value, nil
+		}
+		value, err := loader()
+		if err != nil {
+			return value, err
+		}
+		cache.Set(key, value)
+		return value, nil
+	})
+	// A nil result, such as a failed load of an interface type, doesn't assert to V
+	value, _ := result.(V)
+	return value, err
+}
+
+// Clear removes every entry from the cache. It is safe to call concurrently with the other methods.
+func (cache *memCache[K, V]) Clear() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.cache.Clear()
+	cache.keys.reset()
+}
+
+// Metrics returns a snapshot of the cache statistics, see Metrics.
+func (cache *memCache[K, V]) Metrics() Metrics {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	m := cache.cache.Metrics
+	if m == nil {
+		return Metrics{}
+	}
+	return Metrics{
+		Enabled:     true,
+		Hits:        m.Hits(),
+		Misses:      m.Misses(),
+		KeysAdded:   m.KeysAdded(),
+		KeysEvicted: m.KeysEvicted(),
+		Cost:        m.CostAdded() - m.CostEvicted(),
+		Ratio:       m.Ratio(),
+	}
+}
+
+// Close stops the background goroutines of the cache and drops every entry. It must be called once the cache is
+// no longer needed. After Close, Get always misses, Set returns false and the other methods do nothing.
+func (cache *memCache[K, V]) Close() {
+	// Stop logging first, the logger reads the metrics of the cache
+	if cache.logger != nil {
+		cache.logger.stop()
+	}
+
+	cache.mu.Lock()
+	closed := cache.closed
+	cache.closed = true
+	cache.cache.Close()
+	cache.mu.Unlock()
+
+	// Run the callbacks for the entries dropped by Close before returning
+	if cache.callbacks != nil && !closed {
+		cache.callbacks.close()
+	}
+}
@@ -0,0 +1,36 @@
+package memcache_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/catalogfi/tools/pkg/memcache"
+)
+
+func BenchmarkSet(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		opts []memcache.Options
+	}{
+		{name: "Wait"},
+		{name: "Async", opts: []memcache.Options{memcache.WithAsyncSet()}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			cache, err := memcache.New[int](bench.opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer cache.Close()
+
+			keys := make([]string, 1024)
+			for i := range keys {
+				keys[i] = strconv.Itoa(i)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cache.Set(keys[i%len(keys)], i)
+			}
+		})
+	}
+}
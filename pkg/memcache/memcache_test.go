@@ -1,9 +1,17 @@
 package memcache_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/catalogfi/tools/pkg/memcache"
+	"go.uber.org/goleak"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -18,6 +26,10 @@ var _ = Describe("memCache", func() {
 		Expect(err).Should(BeNil())
 	})
 
+	AfterEach(func() {
+		cache.Close()
+	})
+
 	Context("when setting and getting a value", func() {
 		It("should store and retrieve the value", func() {
 			result := cache.Set("foo", "bar")
@@ -29,6 +41,811 @@ var _ = Describe("memCache", func() {
 		})
 	})
 
+	Context("when setting and getting many values", func() {
+		It("should return the values that were set", func() {
+			cache.SetMany(map[string]string{"foo": "1", "bar": "2", "baz": "3"})
+
+			values := cache.GetMany([]string{"foo", "bar", "baz", "missing"})
+			Expect(values).To(Equal(map[string]string{"foo": "1", "bar": "2", "baz": "3"}))
+		})
+	})
+
+	Context("when preloading values", func() {
+		It("should store the values and report how many were admitted", func() {
+			Expect(cache.Preload(map[string]string{"foo": "1", "bar": "2", "baz": "3"})).To(Equal(3))
+
+			values := cache.GetMany([]string{"foo", "bar", "baz"})
+			Expect(values).To(Equal(map[string]string{"foo": "1", "bar": "2", "baz": "3"}))
+		})
+
+		It("should not count the entries the cache rejected", func() {
+			cache, err := memcache.New[string](memcache.WithMaxCost(100), memcache.WithIgnoreInternalCost(true),
+				memcache.WithCostFunc(func(value string) int64 { return int64(len(value)) }))
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			Expect(cache.Preload(map[string]string{"foo": "1", "bar": "2", "large": strings.Repeat("x", 1000)})).
+				To(Equal(2))
+			Expect(cache.GetMany([]string{"foo", "bar", "large"})).To(Equal(map[string]string{"foo": "1", "bar": "2"}))
+		})
+
+		It("should make the values readable on return even when sets are asynchronous", func() {
+			cache, err := memcache.New[string](memcache.WithAsyncSet())
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			Expect(cache.Preload(map[string]string{"foo": "1", "bar": "2"})).To(Equal(2))
+			Expect(cache.Has("foo")).To(BeTrue())
+			Expect(cache.Has("bar")).To(BeTrue())
+		})
+	})
+
+	Context("when using a non-string key type", func() {
+		type userID int
+
+		It("should store and retrieve values by typed keys", func() {
+			cache, err := memcache.NewKeyed[userID, string]()
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			Expect(cache.Set(1, "alice")).To(BeTrue())
+			Expect(cache.Set(2, "bob")).To(BeTrue())
+
+			value, found := cache.Get(1)
+			Expect(found).To(BeTrue())
+			Expect(value).To(Equal("alice"))
+			Expect(cache.GetMany([]userID{1, 2, 3})).To(Equal(map[userID]string{1: "alice", 2: "bob"}))
+
+			cache.Delete(1)
+			_, found = cache.Get(1)
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Context("when using a context", func() {
+		It("should store and retrieve the value while the context is live", func() {
+			Expect(cache.SetCtx(context.Background(), "foo", "bar")).To(BeTrue())
+
+			value, found := cache.GetCtx(context.Background(), "foo")
+			Expect(found).To(BeTrue())
+			Expect(value).To(Equal("bar"))
+		})
+
+		It("should short-circuit when the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			Expect(cache.SetCtx(ctx, "foo", "bar")).To(BeFalse())
+			_, found := cache.Get("foo")
+			Expect(found).To(BeFalse())
+
+			Expect(cache.Set("foo", "bar")).To(BeTrue())
+			_, found = cache.GetCtx(ctx, "foo")
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Context("when getting a value or a default", func() {
+		It("should return the value on a hit and the default on a miss", func() {
+			Expect(cache.Set("foo", "bar")).To(BeTrue())
+
+			Expect(cache.GetOrDefault("foo", "default")).To(Equal("bar"))
+			Expect(cache.GetOrDefault("missing", "default")).To(Equal("default"))
+		})
+	})
+
+	Context("when checking for a value", func() {
+		It("should report present, absent and expired keys", func() {
+			Expect(cache.Set("foo", "bar")).To(BeTrue())
+			Expect(cache.SetWithTTL("expiring", "bar", 100*time.Millisecond)).To(BeTrue())
+
+			Expect(cache.Has("foo")).To(BeTrue())
+			Expect(cache.Has("missing")).To(BeFalse())
+			Eventually(func() bool { return cache.Has("expiring") }).Should(BeFalse())
+		})
+	})
+
+	Context("when getting a value with its expiry", func() {
+		It("should return when the entry expires", func() {
+			before := time.Now()
+			Expect(cache.SetWithTTL("foo", "bar", time.Minute)).To(BeTrue())
+
+			value, expiresAt, found := cache.GetWithExpiry("foo")
+			Expect(found).To(BeTrue())
+			Expect(value).To(Equal("bar"))
+			Expect(expiresAt).To(BeTemporally(">", before.Add(59*time.Second)))
+			Expect(expiresAt).To(BeTemporally("<=", time.Now().Add(time.Minute)))
+		})
+
+		It("should return a zero time for entries that never expire", func() {
+			Expect(cache.SetWithTTL("foo", "bar", 0)).To(BeTrue())
+
+			_, expiresAt, found := cache.GetWithExpiry("foo")
+			Expect(found).To(BeTrue())
+			Expect(expiresAt.IsZero()).To(BeTrue())
+
+			_, _, found = cache.GetWithExpiry("missing")
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Context("when setting a value only if absent", func() {
+		It("should keep the first value", func() {
+			Expect(cache.SetIfAbsent("foo", "first")).To(BeTrue())
+			Expect(cache.SetIfAbsent("foo", "second")).To(BeFalse())
+
+			value, found := cache.Get("foo")
+			Expect(found).To(BeTrue())
+			Expect(value).To(Equal("first"))
+		})
+	})
+
+	Context("when touching a value", func() {
+		It("should keep the entry past its original expiry", func() {
+			Expect(cache.SetWithTTL("foo", "bar", 500*time.Millisecond)).To(BeTrue())
+			time.Sleep(300 * time.Millisecond)
+
+			Expect(cache.Touch("foo", 2*time.Second)).To(BeTrue())
+			time.Sleep(500 * time.Millisecond)
+
+			value, found := cache.Get("foo")
+			Expect(found).To(BeTrue())
+			Expect(value).To(Equal("bar"))
+		})
+
+		It("should return false for absent keys", func() {
+			Expect(cache.Touch("missing", time.Second)).To(BeFalse())
+			Expect(cache.Has("missing")).To(BeFalse())
+		})
+	})
+
+	Context("when deleting a value", func() {
+		It("should not retrieve the value after deletion", func() {
+			Expect(cache.Set("foo", "bar")).To(BeTrue())
+
+			cache.Delete("foo")
+
+			_, found := cache.Get("foo")
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Context("when deleting many values", func() {
+		It("should remove only the given keys", func() {
+			cache.SetMany(map[string]string{"foo": "1", "bar": "2", "baz": "3", "qux": "4"})
+
+			cache.DeleteMany([]string{"foo", "baz"})
+
+			Expect(cache.GetMany([]string{"foo", "bar", "baz", "qux"})).To(Equal(map[string]string{"bar": "2", "qux": "4"}))
+		})
+	})
+
+	Context("when getting or setting a value", func() {
+		It("should call the loader once on a miss and not at all on a hit", func() {
+			calls := 0
+			loader := func() (string, error) {
+				calls++
+				return "bar", nil
+			}
+
+			value, err := cache.GetOrSet("foo", loader)
+			Expect(err).Should(BeNil())
+			Expect(value).To(Equal("bar"))
+			Expect(calls).To(Equal(1))
+
+			value, err = cache.GetOrSet("foo", loader)
+			Expect(err).Should(BeNil())
+			Expect(value).To(Equal("bar"))
+			Expect(calls).To(Equal(1))
+		})
+
+		It("should not cache loader errors", func() {
+			_, err := cache.GetOrSet("foo", func() (string, error) {
+				return "", errors.New("backend unavailable")
+			})
+			Expect(err).Should(MatchError("backend unavailable"))
+
+			_, found := cache.Get("foo")
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Context("when getting or setting a value with single flight", func() {
+		It("should return the loader error for an interface value type", func() {
+			cache, err := memcache.New[any]()
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			value, err := cache.GetOrSetSingleflight("foo", func() (any, error) {
+				return nil, errors.New("backend unavailable")
+			})
+			Expect(err).Should(MatchError("backend unavailable"))
+			Expect(value).To(BeNil())
+			Expect(cache.Has("foo")).To(BeFalse())
+		})
+
+		It("should call the loader once for concurrent misses", func() {
+			var calls atomic.Int32
+			release := make(chan struct{})
+			loader := func() (string, error) {
+				calls.Add(1)
+				<-release
+				return "bar", nil
+			}
+
+			const callers = 50
+			var wg, started sync.WaitGroup
+			results := make(chan string, callers)
+			for range callers {
+				wg.Add(1)
+				started.Add(1)
+				go func() {
+					defer wg.Done()
+					defer GinkgoRecover()
+					started.Done()
+					value, err := cache.GetOrSetSingleflight("foo", loader)
+					Expect(err).Should(BeNil())
+					results <- value
+				}()
+			}
+			started.Wait()
+			time.Sleep(100 * time.Millisecond)
+			close(release)
+			wg.Wait()
+			close(results)
+
+			Expect(calls.Load()).To(Equal(int32(1)))
+			for value := range results {
+				Expect(value).To(Equal("bar"))
+			}
+		})
+	})
+
+	Context("when clearing the cache", func() {
+		It("should remove every value", func() {
+			keys := []string{"foo", "bar", "baz"}
+			for _, key := range keys {
+				Expect(cache.Set(key, key)).To(BeTrue())
+			}
+
+			cache.Clear()
+
+			for _, key := range keys {
+				_, found := cache.Get(key)
+				Expect(found).To(BeFalse())
+			}
+		})
+
+		It("should be safe to call concurrently with Get and Set", func() {
+			var wg sync.WaitGroup
+			for i := range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer GinkgoRecover()
+					for j := range 100 {
+						key := fmt.Sprintf("key-%d-%d", i, j)
+						cache.Set(key, key)
+						cache.Get(key)
+						if j%10 == 0 {
+							cache.Clear()
+						}
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	})
+
+	Context("when reading metrics", func() {
+		It("should report zeroed metrics when they are disabled", func() {
+			Expect(cache.Set("foo", "bar")).To(BeTrue())
+			cache.Get("foo")
+
+			Expect(cache.Metrics()).To(Equal(memcache.Metrics{}))
+		})
+
+		It("should count hits, misses and added keys when enabled", func() {
+			cache, err := memcache.New[string](memcache.WithMetrics(true))
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			Expect(cache.Set("foo", "bar")).To(BeTrue())
+			Expect(cache.Set("baz", "qux")).To(BeTrue())
+			cache.Get("foo")
+			cache.Get("missing")
+
+			metrics := cache.Metrics()
+			Expect(metrics.Enabled).To(BeTrue())
+			Expect(metrics.Hits).To(Equal(uint64(1)))
+			Expect(metrics.Misses).To(Equal(uint64(1)))
+			Expect(metrics.KeysAdded).To(Equal(uint64(2)))
+			Expect(metrics.Cost).To(BeNumerically(">=", 2))
+			Expect(metrics.Ratio).To(Equal(0.5))
+		})
+
+		It("should log the metrics periodically until closed", func() {
+			var mu sync.Mutex
+			var logged []memcache.Metrics
+			cache, err := memcache.New[string](memcache.WithMetrics(true),
+				memcache.WithMetricsLogger(10*time.Millisecond, func(m memcache.Metrics) {
+					mu.Lock()
+					defer mu.Unlock()
+					logged = append(logged, m)
+				}))
+			Expect(err).Should(BeNil())
+			Expect(cache.Set("foo", "bar")).To(BeTrue())
+
+			count := func() int {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(logged)
+			}
+			Eventually(count).Should(BeNumerically(">=", 1))
+			mu.Lock()
+			Expect(logged[0].Enabled).To(BeTrue())
+			Expect(logged[0].KeysAdded).To(Equal(uint64(1)))
+			mu.Unlock()
+
+			cache.Close()
+			n := count()
+			Consistently(count, 100*time.Millisecond, 10*time.Millisecond).Should(Equal(n))
+		})
+
+		It("should not log when metrics are disabled", func() {
+			var calls atomic.Int32
+			cache, err := memcache.New[string](memcache.WithMetricsLogger(10*time.Millisecond, func(memcache.Metrics) {
+				calls.Add(1)
+			}))
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			Consistently(calls.Load, 100*time.Millisecond, 10*time.Millisecond).Should(BeZero())
+		})
+	})
+
+	Context("when setting a value with its own TTL", func() {
+		It("should expire the short-lived entry and keep the long-lived one", func() {
+			Expect(cache.SetWithTTL("short", "bar", 500*time.Millisecond)).To(BeTrue())
+			Expect(cache.SetWithTTL("long", "bar", time.Hour)).To(BeTrue())
+			Expect(cache.SetWithTTL("forever", "bar", 0)).To(BeTrue())
+
+			time.Sleep(time.Second)
+
+			_, found := cache.Get("short")
+			Expect(found).To(BeFalse())
+			_, found = cache.Get("long")
+			Expect(found).To(BeTrue())
+			_, found = cache.Get("forever")
+			Expect(found).To(BeTrue())
+		})
+	})
+
+	Context("when ignoring the internal cost", func() {
+		It("should admit entries whose cost fits maxCost exactly", func() {
+			cache, err := memcache.New[string](memcache.WithMaxCost(10), memcache.WithIgnoreInternalCost(true))
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			for i := range 10 {
+				Expect(cache.Set(fmt.Sprintf("key-%d", i), "bar")).To(BeTrue())
+			}
+			for i := range 10 {
+				Expect(cache.Has(fmt.Sprintf("key-%d", i))).To(BeTrue())
+			}
+		})
+
+		It("should count the internal cost by default", func() {
+			cache, err := memcache.New[string](memcache.WithMaxCost(10))
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			cache.Set("foo", "bar")
+			Expect(cache.Has("foo")).To(BeFalse())
+		})
+	})
+
+	Context("when entries have different costs", func() {
+		It("should evict a large-cost entry to make room for small ones", func() {
+			if raceEnabled {
+				Skip("the admission policy doesn't see the reads under the race detector")
+			}
+			cache, err := memcache.New[string](memcache.WithMaxCost(10), memcache.WithIgnoreInternalCost(true))
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			Expect(cache.SetWithCost("large", "bar", 6)).To(BeTrue())
+			for i := range 4 {
+				Expect(cache.SetWithCost(fmt.Sprintf("small-%d", i), "bar", 1)).To(BeTrue())
+			}
+			_, found := cache.Get("large")
+			Expect(found).To(BeTrue())
+
+			// the small entries are read more often, so the large one has the lowest frequency when the cache is full.
+			// ristretto drops reads it can't keep up with, pause so that they are counted.
+			for range 20 {
+				for range 20 {
+					for i := range 5 {
+						cache.Get(fmt.Sprintf("small-%d", i))
+					}
+				}
+				time.Sleep(time.Millisecond)
+			}
+			Expect(cache.SetWithCost("small-4", "bar", 1)).To(BeTrue())
+
+			_, found = cache.Get("large")
+			Expect(found).To(BeFalse())
+			for i := range 5 {
+				_, found := cache.Get(fmt.Sprintf("small-%d", i))
+				Expect(found).To(BeTrue())
+			}
+		})
+
+		It("should size entries with the cost function", func() {
+			cache, err := memcache.New[string](memcache.WithMaxCost(1e4), memcache.WithCostFunc(func(value string) int64 {
+				return int64(len(value))
+			}))
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			cache.Set("large", strings.Repeat("x", 2e4))
+			Expect(cache.Set("small", "bar")).To(BeTrue())
+
+			_, found := cache.Get("large")
+			Expect(found).To(BeFalse())
+			_, found = cache.Get("small")
+			Expect(found).To(BeTrue())
+		})
+
+		It("should reject a cost function for another value type", func() {
+			_, err := memcache.New[string](memcache.WithCostFunc(func(value []byte) int64 {
+				return int64(len(value))
+			}))
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("when closing the cache", func() {
+		It("should turn further operations into no-ops", func() {
+			Expect(cache.Set("foo", "bar")).To(BeTrue())
+
+			cache.Close()
+			cache.Close()
+
+			_, found := cache.Get("foo")
+			Expect(found).To(BeFalse())
+			Expect(cache.Set("foo", "bar")).To(BeFalse())
+			cache.Delete("foo")
+			cache.Clear()
+		})
+
+		It("should not leak goroutines", func() {
+			opt := goleak.IgnoreCurrent()
+			for range 50 {
+				cache, err := memcache.New[string]()
+				Expect(err).Should(BeNil())
+				cache.Set("foo", "bar")
+				cache.Close()
+			}
+			goleak.VerifyNone(GinkgoT(), opt)
+		})
+	})
+
+	Context("when the TTL is sliding", func() {
+		It("should keep an entry alive while it is read", func() {
+			cache, err := memcache.New[string](memcache.WithTtl(time.Second), memcache.WithSlidingTTL())
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			Expect(cache.Set("foo", "bar")).To(BeTrue())
+			for range 5 {
+				time.Sleep(500 * time.Millisecond)
+				_, found := cache.Get("foo")
+				Expect(found).To(BeTrue())
+			}
+
+			time.Sleep(1500 * time.Millisecond)
+			_, found := cache.Get("foo")
+			Expect(found).To(BeFalse())
+		})
+
+		It("should renew an entry with the TTL it was set with", func() {
+			cache, err := memcache.New[string](memcache.WithNoExpiry(), memcache.WithSlidingTTL())
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			Expect(cache.SetWithTTL("foo", "bar", 500*time.Millisecond)).To(BeTrue())
+			time.Sleep(300 * time.Millisecond)
+			Expect(cache.Has("foo")).To(BeTrue())
+
+			// Once renewed, the entry lives another 500ms rather than forever with the default of no expiry.
+			// GetWithExpiry doesn't renew it.
+			time.Sleep(400 * time.Millisecond)
+			_, expiresAt, found := cache.GetWithExpiry("foo")
+			Expect(found).To(BeTrue())
+			Expect(expiresAt).NotTo(BeZero())
+
+			time.Sleep(time.Second)
+			_, _, found = cache.GetWithExpiry("foo")
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Context("when entries are rejected", func() {
+		It("should call the rejection function with the key and value", func() {
+			rejected := make(chan string, 16)
+			cache, err := memcache.New[string](memcache.WithMaxCost(100), memcache.WithOnReject(func(key, value string) {
+				rejected <- key + "=" + value
+			}))
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			cache.SetWithCost("large", "bar", 1000)
+			Eventually(rejected).Should(Receive(Equal("large=bar")))
+			Expect(cache.Has("large")).To(BeFalse())
+		})
+	})
+
+	Context("when sets are retried", func() {
+		It("should retry rejected entries up to the configured number of times", func() {
+			var rejections atomic.Int32
+			cache, err := memcache.New[string](
+				memcache.WithMaxCost(100),
+				memcache.WithSetRetries(3),
+				memcache.WithOnReject(func(string, string) { rejections.Add(1) }),
+			)
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			Expect(cache.SetWithCost("large", "bar", 1000)).To(BeFalse())
+			Eventually(rejections.Load).Should(Equal(int32(4)))
+			Consistently(rejections.Load, 100*time.Millisecond).Should(Equal(int32(4)))
+
+			Expect(cache.SetWithCost("small", "bar", 1)).To(BeTrue())
+			Expect(cache.Has("small")).To(BeTrue())
+		})
+
+		It("should store an entry admitted on a retry after being rejected", func() {
+			if raceEnabled {
+				Skip("the admission policy doesn't see the reads under the race detector")
+			}
+			var rejections atomic.Int32
+			cache, err := memcache.New[string](
+				memcache.WithMaxCost(10),
+				memcache.WithIgnoreInternalCost(true),
+				memcache.WithSetRetries(1e5),
+				memcache.WithOnReject(func(string, string) { rejections.Add(1) }),
+			)
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			// A frequently read entry filling the cache makes the admission policy reject a new one, until the reads
+			// of the retries raise its frequency to the same level
+			Expect(cache.SetWithCost("hot", "bar", 10)).To(BeTrue())
+			for range 20 {
+				for range 100 {
+					cache.Get("hot")
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			Expect(cache.SetWithCost("new", "bar", 1)).To(BeTrue())
+			Eventually(rejections.Load).Should(BeNumerically(">=", 1))
+			Expect(cache.Has("new")).To(BeTrue())
+			Expect(cache.Has("hot")).To(BeFalse())
+		})
+	})
+
+	Context("when entries are evicted", func() {
+		It("should call the eviction function with the key and value", func() {
+			var mu sync.Mutex
+			evicted := map[string]string{}
+			var cache memcache.Cache[string]
+			cache, err := memcache.New[string](memcache.WithMaxCost(1000), memcache.WithOnEvict(func(key, value string) {
+				// Calling back into the cache must not deadlock
+				cache.Get(key)
+
+				mu.Lock()
+				defer mu.Unlock()
+				evicted[key] = value
+			}))
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			for i := range 1000 {
+				cache.SetWithCost(fmt.Sprintf("key-%d", i), fmt.Sprintf("value-%d", i), 100)
+			}
+
+			Eventually(func() int {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(evicted)
+			}).Should(BeNumerically(">", 0))
+
+			mu.Lock()
+			defer mu.Unlock()
+			for key, value := range evicted {
+				Expect(value).To(Equal(strings.Replace(key, "key", "value", 1)))
+			}
+		})
+
+		It("should report the entries dropped by Clear and Close", func() {
+			evicted := make(chan string, 16)
+			cache, err := memcache.New[string](memcache.WithOnEvict(func(key, _ string) {
+				evicted <- key
+			}))
+			Expect(err).Should(BeNil())
+
+			Expect(cache.Set("foo", "bar")).To(BeTrue())
+			cache.Clear()
+			Eventually(evicted).Should(Receive(Equal("foo")))
+
+			Expect(cache.Set("baz", "qux")).To(BeTrue())
+			cache.Close()
+			Expect(evicted).To(Receive(Equal("baz")))
+		})
+	})
+
+	Context("when saving and loading a snapshot", func() {
+		BeforeEach(func() {
+			cache.Close()
+			cache, err = memcache.New[string](memcache.WithTtl(5*time.Second), memcache.WithKeyIndex())
+			Expect(err).Should(BeNil())
+		})
+
+		It("should require a key index", func() {
+			unindexed, err := memcache.New[string]()
+			Expect(err).Should(BeNil())
+			defer unindexed.Close()
+			Expect(unindexed.SaveSnapshot(&bytes.Buffer{})).To(MatchError(memcache.ErrNoKeyIndex))
+		})
+
+		It("should restore the values into a fresh cache", func() {
+			cache.SetMany(map[string]string{"foo": "1", "bar": "2"})
+			Expect(cache.SetWithTTL("forever", "3", 0)).To(BeTrue())
+			Expect(cache.Set("deleted", "4")).To(BeTrue())
+			cache.Delete("deleted")
+
+			var buf bytes.Buffer
+			Expect(cache.SaveSnapshot(&buf)).Should(Succeed())
+
+			restored, err := memcache.New[string](memcache.WithTtl(5 * time.Second))
+			Expect(err).Should(BeNil())
+			defer restored.Close()
+			Expect(restored.LoadSnapshot(&buf)).Should(Succeed())
+
+			Expect(restored.GetMany([]string{"foo", "bar", "forever", "deleted"})).To(Equal(map[string]string{
+				"foo":     "1",
+				"bar":     "2",
+				"forever": "3",
+			}))
+		})
+
+		It("should skip entries that expired since the snapshot", func() {
+			Expect(cache.SetWithTTL("short", "1", 200*time.Millisecond)).To(BeTrue())
+			Expect(cache.Set("long", "2")).To(BeTrue())
+
+			var buf bytes.Buffer
+			Expect(cache.SaveSnapshot(&buf)).Should(Succeed())
+			time.Sleep(300 * time.Millisecond)
+
+			restored, err := memcache.New[string]()
+			Expect(err).Should(BeNil())
+			defer restored.Close()
+			Expect(restored.LoadSnapshot(&buf)).Should(Succeed())
+
+			Expect(restored.Has("short")).To(BeFalse())
+			Expect(restored.Has("long")).To(BeTrue())
+		})
+	})
+
+	Context("when listing keys", func() {
+		It("should return nil without a key index", func() {
+			Expect(cache.Set("foo", "bar")).To(BeTrue())
+			Expect(cache.Keys()).To(BeNil())
+		})
+
+		It("should reflect sets, deletes and evictions", func() {
+			indexed, err := memcache.New[string](memcache.WithKeyIndex(), memcache.WithMaxCost(3),
+				memcache.WithIgnoreInternalCost(true))
+			Expect(err).Should(BeNil())
+			defer indexed.Close()
+
+			Expect(indexed.Keys()).To(BeEmpty())
+			indexed.SetMany(map[string]string{"a": "1", "b": "2"})
+			Expect(indexed.SetWithTTL("short", "3", 200*time.Millisecond)).To(BeTrue())
+			Expect(indexed.Keys()).To(ConsistOf("a", "b", "short"))
+
+			indexed.Delete("a")
+			Expect(indexed.Keys()).To(ConsistOf("b", "short"))
+
+			// Expired entries disappear, and a costly entry evicts the others to fit in maxCost
+			Eventually(indexed.Keys).Should(ConsistOf("b"))
+			Expect(indexed.SetWithCost("big", "4", 3)).To(BeTrue())
+			Eventually(indexed.Keys).Should(ConsistOf("big"))
+
+			indexed.Clear()
+			Expect(indexed.Keys()).To(BeEmpty())
+		})
+	})
+
+	Context("when sets are asynchronous", func() {
+		It("should eventually store the value", func() {
+			cache, err := memcache.New[string](memcache.WithAsyncSet())
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			Expect(cache.Set("foo", "bar")).To(BeTrue())
+			Eventually(func() bool { return cache.Has("foo") }).Should(BeTrue())
+		})
+	})
+
+	Context("when the TTL is jittered", func() {
+		It("should spread the expiry of entries set together", func() {
+			cache, err := memcache.New[string](memcache.WithTtl(time.Minute), memcache.WithTTLJitter(0.5))
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			start := time.Now()
+			var earliest, latest time.Duration
+			for i := range 100 {
+				key := fmt.Sprintf("key-%d", i)
+				Expect(cache.Set(key, "bar")).To(BeTrue())
+				_, expiresAt, found := cache.GetWithExpiry(key)
+				Expect(found).To(BeTrue())
+
+				ttl := expiresAt.Sub(start)
+				Expect(ttl).To(BeNumerically(">=", 30*time.Second))
+				Expect(ttl).To(BeNumerically("<=", 91*time.Second))
+				if earliest == 0 || ttl < earliest {
+					earliest = ttl
+				}
+				latest = max(latest, ttl)
+			}
+			Expect(latest - earliest).To(BeNumerically(">", 10*time.Second))
+		})
+
+		It("should never remove the expiry of an entry", func() {
+			cache, err := memcache.New[string](memcache.WithTtl(time.Minute), memcache.WithTTLJitter(1))
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			for i := range 100 {
+				key := fmt.Sprintf("key-%d", i)
+				cache.Set(key, "bar")
+				if _, expiresAt, found := cache.GetWithExpiry(key); found {
+					Expect(expiresAt.IsZero()).To(BeFalse())
+				}
+			}
+		})
+	})
+
+	Context("when no TTL is configured", func() {
+		It("should keep entries well past the cleanup interval", func() {
+			cache, err := memcache.New[string](memcache.WithTtlTickerDurationInSec(1))
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			Expect(cache.Set("foo", "bar")).To(BeTrue())
+			time.Sleep(2500 * time.Millisecond)
+
+			_, expiresAt, found := cache.GetWithExpiry("foo")
+			Expect(found).To(BeTrue())
+			Expect(expiresAt.IsZero()).To(BeTrue())
+		})
+
+		It("should let WithNoExpiry override an earlier TTL", func() {
+			cache, err := memcache.New[string](memcache.WithTtl(time.Second), memcache.WithNoExpiry())
+			Expect(err).Should(BeNil())
+			defer cache.Close()
+
+			Expect(cache.Set("foo", "bar")).To(BeTrue())
+			_, expiresAt, found := cache.GetWithExpiry("foo")
+			Expect(found).To(BeTrue())
+			Expect(expiresAt.IsZero()).To(BeTrue())
+		})
+	})
+
 	Context("when the TTL expires", func() {
 		It("should not retrieve the value after expiration", func() {
 			result := cache.Set("foo", "bar")
@@ -41,3 +858,40 @@ var _ = Describe("memCache", func() {
 		})
 	})
 })
+
+var _ = Describe("IntCache", func() {
+	It("should not lose concurrent increments", func() {
+		cache, err := memcache.NewIntCache[string]()
+		Expect(err).Should(BeNil())
+		defer cache.Close()
+
+		var wg sync.WaitGroup
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 20 {
+					cache.Increment("requests", 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		value, found := cache.Get("requests")
+		Expect(found).To(BeTrue())
+		Expect(value).To(Equal(int64(1000)))
+	})
+
+	It("should keep the expiry of the counter", func() {
+		cache, err := memcache.NewIntCache[string](memcache.WithTtl(time.Minute))
+		Expect(err).Should(BeNil())
+		defer cache.Close()
+
+		Expect(cache.SetWithTTL("requests", 1, 300*time.Millisecond)).To(BeTrue())
+		value, ok := cache.Increment("requests", 2)
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal(int64(3)))
+
+		Eventually(func() bool { return cache.Has("requests") }).Should(BeFalse())
+	})
+})
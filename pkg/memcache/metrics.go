@@ -0,0 +1,44 @@
+package memcache
+
+import (
+	"sync"
+	"time"
+)
+
+// metricsLogger calls a function with the metrics of a cache on a ticker, on its own goroutine, until stopped
+type metricsLogger struct {
+	stopOnce sync.Once
+	done     chan struct{}
+	exited   chan struct{}
+}
+
+// newMetricsLogger starts the goroutine calling log with the result of metrics every interval
+func newMetricsLogger(interval time.Duration, metrics func() Metrics, log func(Metrics)) *metricsLogger {
+	l := &metricsLogger{
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	go l.run(interval, metrics, log)
+	return l
+}
+
+// run calls log on every tick until the logger is stopped
+func (l *metricsLogger) run(interval time.Duration, metrics func() Metrics, log func(Metrics)) {
+	defer close(l.exited)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			log(metrics())
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// stop stops the goroutine and waits for a call to log in progress to return. It may be called several times.
+func (l *metricsLogger) stop() {
+	l.stopOnce.Do(func() { close(l.done) })
+	<-l.exited
+}
@@ -0,0 +1,200 @@
+package memcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// namespace is a view of a Cache whose keys are transparently prefixed
+type namespace[V any] struct {
+	cache  Cache[V]
+	prefix string
+}
+
+// NewNamespace returns a view of cache that prefixes every key with prefix, so that several subsystems can share
+// one cache without colliding. The prefix is invisible to callers: keys are given and returned without it. Include
+// a separator in the prefix, such as "users:", so that no namespace is a prefix of another.
+//
+// The underlying cache must be created with WithKeyIndex, so that Keys and Clear can find the entries of the
+// namespace, otherwise NewNamespace returns ErrNoKeyIndex. Metrics always reports the whole cache. Close does
+// nothing, the underlying cache must be closed by its owner once every view is done with it.
+func NewNamespace[V any](cache Cache[V], prefix string) (Cache[V], error) {
+	if cache.Keys() == nil {
+		return nil, ErrNoKeyIndex
+	}
+	return &namespace[V]{cache: cache, prefix: prefix}, nil
+}
+
+// key returns the key of the underlying cache
+func (n *namespace[V]) key(key string) string {
+	return n.prefix + key
+}
+
+// The methods of the view forward to the underlying cache with prefixed keys, see Cache
+
+func (n *namespace[V]) Get(key string) (V, bool) {
+	return n.cache.Get(n.key(key))
+}
+
+func (n *namespace[V]) Has(key string) bool {
+	return n.cache.Has(n.key(key))
+}
+
+func (n *namespace[V]) GetOrDefault(key string, def V) V {
+	return n.cache.GetOrDefault(n.key(key), def)
+}
+
+func (n *namespace[V]) GetWithExpiry(key string) (V, time.Time, bool) {
+	return n.cache.GetWithExpiry(n.key(key))
+}
+
+func (n *namespace[V]) Set(key string, value V) bool {
+	return n.cache.Set(n.key(key), value)
+}
+
+func (n *namespace[V]) SetWithTTL(key string, value V, ttl time.Duration) bool {
+	return n.cache.SetWithTTL(n.key(key), value, ttl)
+}
+
+func (n *namespace[V]) GetCtx(ctx context.Context, key string) (V, bool) {
+	return n.cache.GetCtx(ctx, n.key(key))
+}
+
+func (n *namespace[V]) SetCtx(ctx context.Context, key string, value V) bool {
+	return n.cache.SetCtx(ctx, n.key(key), value)
+}
+
+func (n *namespace[V]) SetWithCost(key string, value V, cost int64) bool {
+	return n.cache.SetWithCost(n.key(key), value, cost)
+}
+
+func (n *namespace[V]) SetIfAbsent(key string, value V) bool {
+	return n.cache.SetIfAbsent(n.key(key), value)
+}
+
+func (n *namespace[V]) Touch(key string, ttl time.Duration) bool {
+	return n.cache.Touch(n.key(key), ttl)
+}
+
+func (n *namespace[V]) GetMany(keys []string) map[string]V {
+	values := n.cache.GetMany(n.prefixed(keys))
+	result := make(map[string]V, len(values))
+	for key, value := range values {
+		result[strings.TrimPrefix(key, n.prefix)] = value
+	}
+	return result
+}
+
+func (n *namespace[V]) SetMany(entries map[string]V) {
+	n.cache.SetMany(n.prefixedEntries(entries))
+}
+
+func (n *namespace[V]) Preload(entries map[string]V) int {
+	return n.cache.Preload(n.prefixedEntries(entries))
+}
+
+// prefixedEntries returns the entries with the keys of the underlying cache
+func (n *namespace[V]) prefixedEntries(entries map[string]V) map[string]V {
+	prefixed := make(map[string]V, len(entries))
+	for key, value := range entries {
+		prefixed[n.key(key)] = value
+	}
+	return prefixed
+}
+
+func (n *namespace[V]) Delete(key string) {
+	n.cache.Delete(n.key(key))
+}
+
+func (n *namespace[V]) DeleteMany(keys []string) {
+	n.cache.DeleteMany(n.prefixed(keys))
+}
+
+// prefixed returns the keys of the underlying cache
+func (n *namespace[V]) prefixed(keys []string) []string {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = n.key(key)
+	}
+	return prefixed
+}
+
+func (n *namespace[V]) GetOrSet(key string, loader func() (V, error)) (V, error) {
+	return n.cache.GetOrSet(n.key(key), loader)
+}
+
+func (n *namespace[V]) GetOrSetSingleflight(key string, loader func() (V, error)) (V, error) {
+	return n.cache.GetOrSetSingleflight(n.key(key), loader)
+}
+
+func (n *namespace[V]) Clear() {
+	n.cache.DeleteMany(n.prefixed(n.Keys()))
+}
+
+func (n *namespace[V]) Keys() []string {
+	return n.scoped(n.cache.Keys())
+}
+
+// scoped returns the keys of the underlying cache that belong to the namespace, without their prefix
+func (n *namespace[V]) scoped(keys []string) []string {
+	scoped := make([]string, 0)
+	for _, key := range keys {
+		if key, found := strings.CutPrefix(key, n.prefix); found {
+			scoped = append(scoped, key)
+		}
+	}
+	return scoped
+}
+
+func (n *namespace[V]) Metrics() Metrics {
+	return n.cache.Metrics()
+}
+
+// Close does nothing, the underlying cache belongs to its owner
+func (n *namespace[V]) Close() {}
+
+// SaveSnapshot writes the entries of the namespace only, without their prefix.
+func (n *namespace[V]) SaveSnapshot(w io.Writer) error {
+	var buf bytes.Buffer
+	if err := n.cache.SaveSnapshot(&buf); err != nil {
+		return err
+	}
+	var entries []snapshotEntry[string, V]
+	if err := gob.NewDecoder(&buf).Decode(&entries); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	scoped := make([]snapshotEntry[string, V], 0, len(entries))
+	for _, e := range entries {
+		if key, found := strings.CutPrefix(e.Key, n.prefix); found {
+			e.Key = key
+			scoped = append(scoped, e)
+		}
+	}
+	if err := gob.NewEncoder(w).Encode(scoped); err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot adds the entries of a snapshot to the namespace.
+func (n *namespace[V]) LoadSnapshot(r io.Reader) error {
+	var entries []snapshotEntry[string, V]
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	for i := range entries {
+		entries[i].Key = n.key(entries[i].Key)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entries); err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return n.cache.LoadSnapshot(&buf)
+}
@@ -0,0 +1,92 @@
+package memcache_test
+
+import (
+	"bytes"
+
+	"github.com/catalogfi/tools/pkg/memcache"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewNamespace", func() {
+	var cache memcache.Cache[string]
+	var users, orders memcache.Cache[string]
+
+	BeforeEach(func() {
+		var err error
+		cache, err = memcache.New[string](memcache.WithKeyIndex())
+		Expect(err).Should(BeNil())
+		users, err = memcache.NewNamespace(cache, "users:")
+		Expect(err).Should(BeNil())
+		orders, err = memcache.NewNamespace(cache, "orders:")
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		cache.Close()
+	})
+
+	It("should keep the keys of each namespace apart", func() {
+		Expect(users.Set("1", "alice")).To(BeTrue())
+		Expect(orders.Set("1", "order-1")).To(BeTrue())
+
+		Expect(users.GetOrDefault("1", "")).To(Equal("alice"))
+		Expect(orders.GetOrDefault("1", "")).To(Equal("order-1"))
+		Expect(cache.GetOrDefault("users:1", "")).To(Equal("alice"))
+		Expect(cache.Has("1")).To(BeFalse())
+
+		users.Delete("1")
+		Expect(users.Has("1")).To(BeFalse())
+		Expect(orders.Has("1")).To(BeTrue())
+	})
+
+	It("should hide the prefix from batch operations", func() {
+		users.SetMany(map[string]string{"1": "alice", "2": "bob"})
+		orders.Set("3", "order-3")
+		Expect(users.GetMany([]string{"1", "2", "3"})).To(Equal(map[string]string{"1": "alice", "2": "bob"}))
+
+		users.DeleteMany([]string{"1", "2"})
+		Expect(users.GetMany([]string{"1", "2"})).To(BeEmpty())
+
+		Expect(users.Preload(map[string]string{"1": "alice", "2": "bob"})).To(Equal(2))
+		Expect(cache.GetMany([]string{"users:1", "users:2"})).To(HaveLen(2))
+	})
+
+	It("should list and clear only its own keys", func() {
+		users.SetMany(map[string]string{"1": "alice", "2": "bob"})
+		Expect(orders.Set("1", "order-1")).To(BeTrue())
+		Expect(users.Keys()).To(ConsistOf("1", "2"))
+
+		users.Clear()
+		Expect(users.Keys()).To(BeEmpty())
+		Expect(orders.Keys()).To(ConsistOf("1"))
+	})
+
+	It("should require a key index", func() {
+		unindexed, err := memcache.New[string]()
+		Expect(err).Should(BeNil())
+		defer unindexed.Close()
+
+		_, err = memcache.NewNamespace(unindexed, "users:")
+		Expect(err).Should(MatchError(memcache.ErrNoKeyIndex))
+	})
+
+	It("should snapshot only its own entries", func() {
+		Expect(users.Set("1", "alice")).To(BeTrue())
+		Expect(orders.Set("1", "order-1")).To(BeTrue())
+
+		var buf bytes.Buffer
+		Expect(users.SaveSnapshot(&buf)).To(Succeed())
+
+		restored := memcache.NewFake[string](nil)
+		Expect(restored.LoadSnapshot(bytes.NewReader(buf.Bytes()))).To(Succeed())
+		Expect(restored.GetMany([]string{"1"})).To(Equal(map[string]string{"1": "alice"}))
+		Expect(restored.Metrics().Cost).To(Equal(uint64(1)))
+
+		admins, err := memcache.NewNamespace(cache, "admins:")
+		Expect(err).Should(BeNil())
+		Expect(admins.LoadSnapshot(&buf)).To(Succeed())
+		Expect(cache.GetOrDefault("admins:1", "")).To(Equal("alice"))
+	})
+})
@@ -0,0 +1,7 @@
+//go:build race
+
+package memcache_test
+
+// raceEnabled reports whether the tests run under the race detector, which makes sync.Pool drop items at random
+// and with them the reads ristretto buffers for its admission policy.
+const raceEnabled = true
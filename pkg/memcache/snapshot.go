@@ -0,0 +1,144 @@
+package memcache
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrNoKeyIndex is returned by SaveSnapshot and NewNamespace when the cache wasn't created with WithKeyIndex
+var ErrNoKeyIndex = errors.New("the cache has no key index, create it with WithKeyIndex")
+
+// keyIndex tracks the keys stored in the cache, since ristretto can't enumerate its entries. It may briefly hold
+// keys that were rejected or already evicted, so readers must check the cache for every key. A nil index records
+// nothing, which is what caches without WithKeyIndex use.
+type keyIndex[K comparable] struct {
+	mu   sync.Mutex
+	keys map[K]struct{}
+}
+
+// newKeyIndex returns an empty key index
+func newKeyIndex[K comparable]() *keyIndex[K] {
+	return &keyIndex[K]{keys: make(map[K]struct{})}
+}
+
+// add records key
+func (index *keyIndex[K]) add(key K) {
+	if index == nil {
+		return
+	}
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.keys[key] = struct{}{}
+}
+
+// remove forgets key
+func (index *keyIndex[K]) remove(key K) {
+	if index == nil {
+		return
+	}
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	delete(index.keys, key)
+}
+
+// reset forgets every key
+func (index *keyIndex[K]) reset() {
+	if index == nil {
+		return
+	}
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.keys = make(map[K]struct{})
+}
+
+// list returns a copy of the recorded keys
+func (index *keyIndex[K]) list() []K {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	keys := make([]K, 0, len(index.keys))
+	for key := range index.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// snapshotEntry is a single gob-encoded entry of a snapshot
+type snapshotEntry[K comparable, V any] struct {
+	Key       K
+	Value     V
+	ExpiresAt time.Time
+}
+
+// Keys returns the keys currently in the cache, in no particular order. It returns nil when the cache wasn't
+// created with WithKeyIndex, since ristretto can't enumerate its entries.
+func (cache *memCache[K, V]) Keys() []K {
+	if cache.keys == nil {
+		return nil
+	}
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	keys := make([]K, 0)
+	for _, key := range cache.keys.list() {
+		if _, _, found := cache.lookup(key); found {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// SaveSnapshot writes every entry currently in the cache to w as a gob stream, along with its expiry. Keys and
+// values must be encodable with encoding/gob, which ignores unexported struct fields. Per-entry costs aren't
+// saved, restored entries are sized with the cost function or the default cost of 1. The cache must be created
+// with WithKeyIndex to know which entries to save, otherwise ErrNoKeyIndex is returned.
+func (cache *memCache[K, V]) SaveSnapshot(w io.Writer) error {
+	if cache.keys == nil {
+		return ErrNoKeyIndex
+	}
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	entries := make([]snapshotEntry[K, V], 0)
+	for _, key := range cache.keys.list() {
+		value, expiresAt, found := cache.lookup(key)
+		if !found {
+			continue
+		}
+		entries = append(entries, snapshotEntry[K, V]{Key: key, Value: value, ExpiresAt: expiresAt})
+	}
+
+	if err := gob.NewEncoder(w).Encode(entries); err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot adds the entries written by SaveSnapshot to the cache, skipping the ones that have expired since.
+// Restored entries keep their original expiry, and entries without expiry are stored without one.
+func (cache *memCache[K, V]) LoadSnapshot(r io.Reader) error {
+	var entries []snapshotEntry[K, V]
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	now := time.Now()
+	for _, e := range entries {
+		var ttl time.Duration
+		if !e.ExpiresAt.IsZero() {
+			ttl = e.ExpiresAt.Sub(now)
+			if ttl <= 0 {
+				continue
+			}
+		}
+		cache.store(e.Key, e.Value, cache.cost(e.Value), ttl)
+	}
+	cache.cache.Wait()
+	return nil
+}
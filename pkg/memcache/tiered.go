@@ -0,0 +1,81 @@
+package memcache
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Backing is the second tier of a TieredCache, typically a remote store such as Redis
+type Backing[V any] interface {
+	// Load returns the value of key, with false when the store doesn't hold it
+	Load(ctx context.Context, key string) (V, bool, error)
+	// Store saves the value of key
+	Store(ctx context.Context, key string, value V) error
+}
+
+// TieredCache puts an in-memory Cache in front of a Backing store. Reads are served from the cache when possible and
+// fall through to the store on a miss, populating the cache. Writes go through to both tiers.
+type TieredCache[V any] struct {
+	l1    Cache[V]
+	l2    Backing[V]
+	group singleflight.Group
+}
+
+// tieredResult is the outcome of a load shared by concurrent misses
+type tieredResult[V any] struct {
+	value V
+	found bool
+}
+
+// NewTieredCache returns a TieredCache reading through l1 to l2. The entries it adds to l1 use the TTL l1 was
+// created with, which bounds how stale they can get when l2 is updated by someone else. Closing l1 is left to the
+// caller.
+func NewTieredCache[V any](l1 Cache[V], l2 Backing[V]) *TieredCache[V] {
+	return &TieredCache[V]{l1: l1, l2: l2}
+}
+
+// Get returns the value of key from the cache, or else from the backing store, storing it in the cache. The
+// boolean is false when neither tier holds the key. Concurrent misses for the same key share a single load.
+func (t *TieredCache[V]) Get(ctx context.Context, key string) (V, bool, error) {
+	if value, found := t.l1.GetCtx(ctx, key); found {
+		return value, true, nil
+	}
+
+	result, err, _ := t.group.Do(key, func() (any, error) {
+		// Another load may have populated the cache between the miss above and this call
+		if value, found := t.l1.Get(key); found {
+			return tieredResult[V]{value: value, found: true}, nil
+		}
+		value, found, err := t.l2.Load(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %q from the backing store: %w", key, err)
+		}
+		if found {
+			t.l1.Set(key, value)
+		}
+		return tieredResult[V]{value: value, found: found}, nil
+	})
+	if err != nil {
+		var zero V
+		return zero, false, err
+	}
+	r := result.(tieredResult[V])
+	return r.value, r.found, nil
+}
+
+// Set stores the value in the backing store, then in the cache. When the backing store fails, the cache is left
+// untouched so that it doesn't serve a value the store doesn't have.
+func (t *TieredCache[V]) Set(ctx context.Context, key string, value V) error {
+	if err := t.l2.Store(ctx, key, value); err != nil {
+		return fmt.Errorf("failed to store %q in the backing store: %w", key, err)
+	}
+	t.l1.Set(key, value)
+	return nil
+}
+
+// Invalidate removes key from the cache so that the next Get loads it from the backing store again
+func (t *TieredCache[V]) Invalidate(key string) {
+	t.l1.Delete(key)
+}
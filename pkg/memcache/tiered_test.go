@@ -0,0 +1,92 @@
+package memcache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/catalogfi/tools/pkg/memcache"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// mockBacking is an in-memory Backing counting its calls
+type mockBacking struct {
+	mu       sync.Mutex
+	values   map[string]string
+	loads    int
+	stores   int
+	storeErr error
+}
+
+func (m *mockBacking) Load(_ context.Context, key string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loads++
+	value, found := m.values[key]
+	return value, found, nil
+}
+
+func (m *mockBacking) Store(_ context.Context, key string, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stores++
+	if m.storeErr != nil {
+		return m.storeErr
+	}
+	m.values[key] = value
+	return nil
+}
+
+var _ = Describe("TieredCache", func() {
+	var l1 *memcache.Fake[string]
+	var l2 *mockBacking
+	var cache *memcache.TieredCache[string]
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		l1 = memcache.NewFake[string](nil)
+		l2 = &mockBacking{values: map[string]string{"foo": "bar"}}
+		cache = memcache.NewTieredCache[string](l1, l2)
+	})
+
+	It("should populate the cache on a miss and serve hits without the backing store", func() {
+		value, found, err := cache.Get(ctx, "foo")
+		Expect(err).Should(BeNil())
+		Expect(found).To(BeTrue())
+		Expect(value).To(Equal("bar"))
+		Expect(l2.loads).To(Equal(1))
+		Expect(l1.Has("foo")).To(BeTrue())
+
+		for range 3 {
+			value, found, err = cache.Get(ctx, "foo")
+			Expect(err).Should(BeNil())
+			Expect(found).To(BeTrue())
+			Expect(value).To(Equal("bar"))
+		}
+		Expect(l2.loads).To(Equal(1))
+
+		cache.Invalidate("foo")
+		_, _, err = cache.Get(ctx, "foo")
+		Expect(err).Should(BeNil())
+		Expect(l2.loads).To(Equal(2))
+	})
+
+	It("should report keys missing from both tiers", func() {
+		_, found, err := cache.Get(ctx, "missing")
+		Expect(err).Should(BeNil())
+		Expect(found).To(BeFalse())
+		Expect(l1.Has("missing")).To(BeFalse())
+	})
+
+	It("should write through to both tiers", func() {
+		Expect(cache.Set(ctx, "baz", "qux")).To(Succeed())
+		Expect(l2.values).To(HaveKeyWithValue("baz", "qux"))
+		Expect(l1.GetOrDefault("baz", "")).To(Equal("qux"))
+
+		l2.storeErr = errors.New("unavailable")
+		Expect(cache.Set(ctx, "baz", "other")).To(MatchError(ContainSubstring("unavailable")))
+		Expect(l1.GetOrDefault("baz", "")).To(Equal("qux"))
+	})
+})
@@ -0,0 +1,62 @@
+package totp
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/boombuler/barcode/qr"
+	"github.com/pquerna/otp"
+)
+
+// quietZone is the number of light modules around the QR code that scanners need to find it
+const quietZone = 4
+
+// RenderASCII renders the QR code of the key with Unicode block characters, two rows of modules per line. The
+// light modules, including the quiet zone, are drawn as blocks, which scans correctly on terminals with light text
+// on a dark background. Use RenderASCIIInverted for terminals with dark text on a light background.
+func RenderASCII(key *otp.Key) (string, error) {
+	return renderASCII(key, false)
+}
+
+// RenderASCIIInverted is like RenderASCII but draws the dark modules as blocks, for terminals with dark text on a
+// light background
+func RenderASCIIInverted(key *otp.Key) (string, error) {
+	return renderASCII(key, true)
+}
+
+// renderASCII draws the QR code, with blocks for light modules unless inverted
+func renderASCII(key *otp.Key, inverted bool) (string, error) {
+	code, err := qr.Encode(key.URL(), qr.M, qr.Auto)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode qr code: %w", err)
+	}
+
+	size := code.Bounds().Dx()
+	// filled reports whether the module is drawn as a block, modules outside the code belong to the quiet zone
+	filled := func(x, y int) bool {
+		x, y = x-quietZone, y-quietZone
+		light := x < 0 || y < 0 || x >= size || y >= size || code.At(x, y) == color.White
+		return light != inverted
+	}
+
+	var b strings.Builder
+	total := size + 2*quietZone
+	for y := 0; y < total; y += 2 {
+		for x := 0; x < total; x++ {
+			top, bottom := filled(x, y), y+1 < total && filled(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteRune('█')
+			case top:
+				b.WriteRune('▀')
+			case bottom:
+				b.WriteRune('▄')
+			default:
+				b.WriteRune(' ')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,48 @@
+package totp_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/catalogfi/tools/pkg/totp"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderASCII verifies that the QR code is surrounded by a quiet zone of light modules.
+func TestRenderASCII(t *testing.T) {
+	key, err := totp.GenerateSecret("moji", "alice@example.com")
+	require.NoError(t, err)
+
+	ascii, err := totp.RenderASCII(key)
+	require.NoError(t, err)
+	require.NotEmpty(t, ascii)
+
+	lines := strings.Split(strings.TrimSuffix(ascii, "\n"), "\n")
+	width := utf8.RuneCountInString(lines[0])
+	for _, line := range lines {
+		require.Equal(t, width, utf8.RuneCountInString(line), "lines should have the same width")
+	}
+
+	// The four module quiet zone spans the first two lines and the first four columns
+	quiet := strings.Repeat("█", width)
+	require.Equal(t, quiet, lines[0])
+	require.Equal(t, quiet, lines[1])
+	for _, line := range lines[2 : len(lines)-2] {
+		require.True(t, strings.HasPrefix(line, "████"), "line %q should start with the quiet zone", line)
+		require.True(t, strings.HasSuffix(line, "████"), "line %q should end with the quiet zone", line)
+	}
+}
+
+// TestRenderASCIIInverted verifies that the inverted rendering leaves the quiet zone blank.
+func TestRenderASCIIInverted(t *testing.T) {
+	key, err := totp.GenerateSecret("moji", "alice@example.com")
+	require.NoError(t, err)
+
+	ascii, err := totp.RenderASCIIInverted(key)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(ascii, "\n"), "\n")
+	require.Equal(t, strings.Repeat(" ", utf8.RuneCountInString(lines[0])), lines[0])
+	require.Contains(t, ascii, "█")
+}
@@ -0,0 +1,85 @@
+package totp
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+)
+
+// DefaultHOTPLookAhead is how many counter values past the expected one an HOTPCounter accepts, see HOTPCounter
+const DefaultHOTPLookAhead = 10
+
+// GenerateHOTPSecret generates a new random secret key for counter-based one-time passwords. The period option
+// doesn't apply to HOTP and is ignored.
+func GenerateHOTPSecret(issuer, accountName string, opts ...Option) (*otp.Key, error) {
+	o := applyOptions(append([]Option{WithIssuer(issuer)}, opts...))
+	key, err := hotp.Generate(hotp.GenerateOpts{
+		Issuer:      o.issuer,
+		AccountName: accountName,
+		Digits:      o.digits,
+		Algorithm:   o.algorithm,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate hotp secret: %w", err)
+	}
+	return key, nil
+}
+
+// ValidateHOTP reports whether code is the code of the secret for exactly this counter value. The options must
+// match the ones the secret was generated with.
+func ValidateHOTP(secret, code string, counter uint64, opts ...Option) bool {
+	o := applyOptions(opts)
+	valid, err := hotp.ValidateCustom(code, counter, secret, hotp.ValidateOpts{
+		Digits:    o.digits,
+		Algorithm: o.algorithm,
+	})
+	return err == nil && valid
+}
+
+// HOTPCounter tracks the counter of an HOTP secret on the validating side. A token advances its own counter every
+// time it generates a code, including codes that are never submitted, so the two counters drift apart. To resync,
+// Validate accepts a code for any of the next lookAhead counter values and then moves past the counter that
+// matched, which also rejects every code at or before it from then on.
+type HOTPCounter struct {
+	mu        sync.Mutex
+	secret    string
+	counter   uint64
+	lookAhead uint64
+	opts      []Option
+}
+
+// NewHOTPCounter returns a counter for the secret expecting the code of counter next, with the default look-ahead.
+// The options must match the ones the secret was generated with, see ValidateHOTP.
+func NewHOTPCounter(secret string, counter uint64, opts ...Option) *HOTPCounter {
+	return NewHOTPCounterWithLookAhead(secret, counter, DefaultHOTPLookAhead, opts...)
+}
+
+// NewHOTPCounterWithLookAhead is like NewHOTPCounter but accepts codes up to lookAhead counter values ahead. A
+// lookAhead of 0 only accepts the code of the expected counter.
+func NewHOTPCounterWithLookAhead(secret string, counter, lookAhead uint64, opts ...Option) *HOTPCounter {
+	return &HOTPCounter{secret: secret, counter: counter, lookAhead: lookAhead, opts: opts}
+}
+
+// Counter returns the counter value whose code is expected next, to be persisted between validations
+func (c *HOTPCounter) Counter() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counter
+}
+
+// Validate reports whether code matches the expected counter or one within the look-ahead window, advancing the
+// counter past the match when it does
+func (c *HOTPCounter) Validate(code string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for counter := c.counter; counter <= c.counter+c.lookAhead; counter++ {
+		if ValidateHOTP(c.secret, code, counter, c.opts...) {
+			c.counter = counter + 1
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package totp_test
+
+import (
+	"testing"
+
+	"github.com/catalogfi/tools/pkg/totp"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidateHOTP verifies codes for a sequence of counter values.
+func TestValidateHOTP(t *testing.T) {
+	key, err := totp.GenerateHOTPSecret("moji", "alice@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "hotp", key.Type())
+
+	for counter := uint64(0); counter < 5; counter++ {
+		code, err := hotp.GenerateCode(key.Secret(), counter)
+		require.NoError(t, err)
+		require.True(t, totp.ValidateHOTP(key.Secret(), code, counter))
+		require.False(t, totp.ValidateHOTP(key.Secret(), code, counter+1))
+	}
+}
+
+// TestHOTPCounter verifies that the counter advances, resyncs within the look-ahead and rejects replays.
+func TestHOTPCounter(t *testing.T) {
+	key, err := totp.GenerateHOTPSecret("moji", "alice@example.com")
+	require.NoError(t, err)
+	code := func(counter uint64) string {
+		code, err := hotp.GenerateCode(key.Secret(), counter)
+		require.NoError(t, err)
+		return code
+	}
+
+	counter := totp.NewHOTPCounterWithLookAhead(key.Secret(), 0, 3)
+	require.True(t, counter.Validate(code(0)))
+	require.Equal(t, uint64(1), counter.Counter())
+
+	// The token generated codes 1 and 2 without submitting them
+	require.True(t, counter.Validate(code(3)))
+	require.Equal(t, uint64(4), counter.Counter())
+
+	require.False(t, counter.Validate(code(3)), "replayed code should be rejected")
+	require.False(t, counter.Validate(code(8)), "code beyond the look-ahead should be rejected")
+	require.Equal(t, uint64(4), counter.Counter())
+}
+
+// TestHOTPCounterOptions verifies that the counter validates codes of secrets with non-default parameters.
+func TestHOTPCounterOptions(t *testing.T) {
+	opts := []totp.Option{totp.WithDigits(otp.DigitsEight), totp.WithAlgorithm(otp.AlgorithmSHA512)}
+	key, err := totp.GenerateHOTPSecret("moji", "alice@example.com", opts...)
+	require.NoError(t, err)
+	code, err := hotp.GenerateCodeCustom(key.Secret(), 2, hotp.ValidateOpts{
+		Digits:    otp.DigitsEight,
+		Algorithm: otp.AlgorithmSHA512,
+	})
+	require.NoError(t, err)
+	require.Len(t, code, 8)
+
+	require.False(t, totp.NewHOTPCounter(key.Secret(), 0).Validate(code), "default options shouldn't match")
+
+	counter := totp.NewHOTPCounter(key.Secret(), 0, opts...)
+	require.True(t, counter.Validate(code))
+	require.Equal(t, uint64(3), counter.Counter())
+}
@@ -0,0 +1,65 @@
+package totp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// recoveryAlphabet holds the characters of recovery codes, uppercase so they are easy to read out and type
+const recoveryAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// ErrInvalidRecoveryParams is returned when the number or length of recovery codes isn't positive
+var ErrInvalidRecoveryParams = errors.New("recovery code count and length must be positive")
+
+// GenerateRecoveryCodes returns n distinct, cryptographically random alphanumeric codes of the given length that
+// let a user sign in without their authenticator. Show the codes to the user once and only store their hashes,
+// see HashRecoveryCode. A length of 10 or more keeps them impractical to guess.
+func GenerateRecoveryCodes(n, length int) ([]string, error) {
+	if n <= 0 || length <= 0 {
+		return nil, ErrInvalidRecoveryParams
+	}
+	alphabetSize := big.NewInt(int64(len(recoveryAlphabet)))
+	if combinations := new(big.Int).Exp(alphabetSize, big.NewInt(int64(length)), nil); combinations.Cmp(big.NewInt(int64(n))) < 0 {
+		return nil, fmt.Errorf("cannot generate %d distinct recovery codes of length %d", n, length)
+	}
+
+	codes := make([]string, 0, n)
+	seen := make(map[string]struct{}, n)
+	for len(codes) < n {
+		var b strings.Builder
+		for range length {
+			i, err := rand.Int(rand.Reader, alphabetSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+			}
+			b.WriteByte(recoveryAlphabet[i.Int64()])
+		}
+
+		code := b.String()
+		if _, ok := seen[code]; ok {
+			continue
+		}
+		seen[code] = struct{}{}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns the hex SHA-256 hash of the code to store in place of the code itself. Codes are
+// compared case-insensitively and surrounding whitespace is ignored. A fast hash is enough since the codes are
+// random rather than chosen by users.
+func HashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToUpper(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyRecoveryCode reports whether code matches a hash returned by HashRecoveryCode, in constant time
+func VerifyRecoveryCode(code, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashRecoveryCode(code)), []byte(strings.ToLower(hash))) == 1
+}
@@ -0,0 +1,47 @@
+package totp_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/catalogfi/tools/pkg/totp"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerateRecoveryCodes verifies the count, length, character set and uniqueness of recovery codes.
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := totp.GenerateRecoveryCodes(100, 10)
+	require.NoError(t, err)
+	require.Len(t, codes, 100)
+
+	seen := make(map[string]struct{})
+	for _, code := range codes {
+		require.Len(t, code, 10)
+		require.Empty(t, strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"), "code %q should be alphanumeric", code)
+		require.NotContains(t, seen, code, "codes should be unique")
+		seen[code] = struct{}{}
+	}
+
+	// Short codes force duplicates to be regenerated
+	codes, err = totp.GenerateRecoveryCodes(36, 1)
+	require.NoError(t, err)
+	require.ElementsMatch(t, strings.Split("ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789", ""), codes)
+
+	_, err = totp.GenerateRecoveryCodes(37, 1)
+	require.Error(t, err)
+
+	_, err = totp.GenerateRecoveryCodes(0, 10)
+	require.ErrorIs(t, err, totp.ErrInvalidRecoveryParams)
+}
+
+// TestHashRecoveryCode verifies that hashed codes can be verified without storing the code.
+func TestHashRecoveryCode(t *testing.T) {
+	codes, err := totp.GenerateRecoveryCodes(2, 10)
+	require.NoError(t, err)
+
+	hash := totp.HashRecoveryCode(codes[0])
+	require.NotContains(t, hash, codes[0])
+	require.True(t, totp.VerifyRecoveryCode(codes[0], hash))
+	require.True(t, totp.VerifyRecoveryCode(" "+strings.ToLower(codes[0])+"\n", hash))
+	require.False(t, totp.VerifyRecoveryCode(codes[1], hash))
+}
@@ -0,0 +1,25 @@
+package totp
+
+import (
+	"fmt"
+
+	"github.com/catalogfi/tools/pkg/cryptutil"
+)
+
+// EncryptSecret encrypts a secret with AES-256-GCM so it can be stored at rest, returning the ciphertext as hex
+func EncryptSecret(a *cryptutil.AES256, secret string) (string, error) {
+	blob, err := a.EncryptStringToHex(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+	return blob, nil
+}
+
+// DecryptSecret decrypts a hex blob produced by EncryptSecret back into the secret
+func DecryptSecret(a *cryptutil.AES256, blob string) (string, error) {
+	secret, err := a.DecryptHexToString(blob)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return secret, nil
+}
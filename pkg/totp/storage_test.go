@@ -0,0 +1,29 @@
+package totp_test
+
+import (
+	"testing"
+
+	"github.com/catalogfi/tools/pkg/cryptutil"
+	"github.com/catalogfi/tools/pkg/totp"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEncryptSecret verifies that an encrypted secret decrypts back to the original and still validates codes.
+func TestEncryptSecret(t *testing.T) {
+	a, err := cryptutil.NewAES256("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	require.NoError(t, err)
+
+	key, err := totp.GenerateSecret("Test", "test@example.com")
+	require.NoError(t, err)
+
+	blob, err := totp.EncryptSecret(a, key.Secret())
+	require.NoError(t, err)
+	require.NotContains(t, blob, key.Secret())
+
+	secret, err := totp.DecryptSecret(a, blob)
+	require.NoError(t, err)
+	require.Equal(t, key.Secret(), secret)
+
+	_, err = totp.DecryptSecret(a, blob[:len(blob)-2])
+	require.Error(t, err)
+}
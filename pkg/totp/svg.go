@@ -0,0 +1,46 @@
+package totp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+
+	"github.com/boombuler/barcode/qr"
+	"github.com/pquerna/otp"
+)
+
+// ErrInvalidSize is returned when the requested size of a rendered QR code isn't positive
+var ErrInvalidSize = errors.New("size must be positive")
+
+// RenderSVG renders the QR code of the key as a self-contained size x size SVG document. The modules are drawn in a
+// single path on a white background, including the quiet zone, and the output is identical for identical inputs so
+// it can be cached.
+func RenderSVG(key *otp.Key, size int) ([]byte, error) {
+	if size <= 0 {
+		return nil, ErrInvalidSize
+	}
+	code, err := qr.Encode(key.URL(), qr.M, qr.Auto)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode qr code: %w", err)
+	}
+
+	modules := code.Bounds().Dx()
+	total := modules + 2*quietZone
+
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		size, size, total, total)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", total, total)
+	b.WriteString(`<path fill="#000000" d="`)
+	for y := 0; y < modules; y++ {
+		for x := 0; x < modules; x++ {
+			if code.At(x, y) != color.White {
+				fmt.Fprintf(&b, "M%d %dh1v1h-1z", x+quietZone, y+quietZone)
+			}
+		}
+	}
+	b.WriteString(`"/>` + "\n</svg>\n")
+	return b.Bytes(), nil
+}
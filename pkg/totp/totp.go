@@ -0,0 +1,189 @@
+// Package totp generates time-based one-time password secrets and renders them as QR codes for authenticator apps.
+package totp
+
+import (
+	"bytes"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	pqtotp "github.com/pquerna/otp/totp"
+)
+
+// Bounds on the length of a secret in base32 characters, without padding. 16 characters hold the 80 bits most
+// authenticator apps generate, and anything beyond 128 characters is more likely a pasted blob than a secret.
+const (
+	minSecretLength = 16
+	maxSecretLength = 128
+)
+
+// Errors returned by ValidateSecret
+var (
+	ErrInvalidSecretCharacter = errors.New("secret contains a character outside the base32 alphabet")
+	ErrInvalidSecretLength    = errors.New("secret has an invalid length")
+)
+
+// Option is a functional option type for configuring the generated secrets
+type Option func(*options)
+
+// options holds the parameters of a generated secret
+type options struct {
+	issuer    string
+	period    uint
+	digits    otp.Digits
+	algorithm otp.Algorithm
+}
+
+// defaultOptions returns the parameters supported by most authenticator apps
+func defaultOptions() *options {
+	return &options{
+		period:    30,
+		digits:    otp.DigitsSix,
+		algorithm: otp.AlgorithmSHA1,
+	}
+}
+
+// applyOptions returns the default options with opts applied on top
+func applyOptions(opts []Option) *options {
+	defaultOpts := defaultOptions()
+	for _, opt := range opts {
+		opt(defaultOpts)
+	}
+	return defaultOpts
+}
+
+// WithIssuer sets the issuer shown by authenticator apps, overriding the one passed to GenerateSecret
+func WithIssuer(issuer string) Option {
+	return func(opts *options) {
+		opts.issuer = issuer
+	}
+}
+
+// WithPeriod sets how many seconds each code stays valid, 30 by default
+func WithPeriod(period uint) Option {
+	return func(opts *options) {
+		opts.period = period
+	}
+}
+
+// WithDigits sets the number of digits of the codes, six by default
+func WithDigits(digits otp.Digits) Option {
+	return func(opts *options) {
+		opts.digits = digits
+	}
+}
+
+// WithAlgorithm sets the HMAC algorithm of the codes, SHA1 by default. Not every authenticator app supports SHA256
+// and SHA512.
+func WithAlgorithm(algorithm otp.Algorithm) Option {
+	return func(opts *options) {
+		opts.algorithm = algorithm
+	}
+}
+
+// GenerateSecret generates a new random secret key for the account
+func GenerateSecret(issuer, accountName string, opts ...Option) (*otp.Key, error) {
+	key, err := newKey(issuer, accountName, nil, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return key, nil
+}
+
+// KeyFromSecret rebuilds the key of an existing base32 secret, for example to show its QR code again when a user
+// enrolls a new device. The options must match the ones the secret was generated with. Lowercase secrets and
+// secrets with or without padding are accepted.
+func KeyFromSecret(issuer, accountName, secret string, opts ...Option) (*otp.Key, error) {
+	decoded, err := decodeSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	key, err := newKey(issuer, accountName, decoded, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build key: %w", err)
+	}
+	return key, nil
+}
+
+// newKey builds a key for the secret, generating a random one when secret is nil
+func newKey(issuer, accountName string, secret []byte, opts []Option) (*otp.Key, error) {
+	o := applyOptions(append([]Option{WithIssuer(issuer)}, opts...))
+	return pqtotp.Generate(pqtotp.GenerateOpts{
+		Issuer:      o.issuer,
+		AccountName: accountName,
+		Period:      o.period,
+		Secret:      secret,
+		Digits:      o.digits,
+		Algorithm:   o.algorithm,
+	})
+}
+
+// ValidateSecret checks that a secret is base32 of a reasonable length before a key is built from it. Like
+// KeyFromSecret, it ignores case, surrounding whitespace and padding. It returns an error wrapping
+// ErrInvalidSecretCharacter or ErrInvalidSecretLength.
+func ValidateSecret(secret string) error {
+	normalized := strings.TrimRight(strings.ToUpper(strings.TrimSpace(secret)), "=")
+	for i, r := range normalized {
+		if !strings.ContainsRune("ABCDEFGHIJKLMNOPQRSTUVWXYZ234567", r) {
+			return fmt.Errorf("%w: %q at position %d", ErrInvalidSecretCharacter, r, i)
+		}
+	}
+	if len(normalized) < minSecretLength || len(normalized) > maxSecretLength {
+		return fmt.Errorf("%w: %d characters, expected between %d and %d",
+			ErrInvalidSecretLength, len(normalized), minSecretLength, maxSecretLength)
+	}
+	// Base32 encodes 5 bytes in 8 characters, so a final group of 1, 3 or 6 characters can't occur
+	switch len(normalized) % 8 {
+	case 1, 3, 6:
+		return fmt.Errorf("%w: %d characters aren't a whole number of bytes", ErrInvalidSecretLength, len(normalized))
+	}
+	return nil
+}
+
+// decodeSecret decodes a base32 secret, ignoring case and padding
+func decodeSecret(secret string) ([]byte, error) {
+	normalized := strings.TrimRight(strings.ToUpper(strings.TrimSpace(secret)), "=")
+	decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("secret is not valid base32: %w", err)
+	}
+	return decoded, nil
+}
+
+// RenderPNG encodes the key as a size x size PNG image of its QR code
+func RenderPNG(key *otp.Key, size int) ([]byte, error) {
+	img, err := key.Image(size, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render qr code: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode qr code: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Validate reports whether code is valid for the secret at the current time. The options must match the ones the
+// secret was generated with. Like most authenticator servers, it accepts the codes of the previous and next time
+// steps to tolerate clock drift.
+func Validate(secret, code string, opts ...Option) bool {
+	return ValidateWithWindow(secret, code, 1, opts...)
+}
+
+// ValidateWithWindow is like Validate but accepts the codes of up to skew time steps before and after the current
+// one. A skew of 0 only accepts the current code.
+func ValidateWithWindow(secret, code string, skew uint, opts ...Option) bool {
+	o := applyOptions(opts)
+	valid, err := pqtotp.ValidateCustom(code, secret, time.Now().UTC(), pqtotp.ValidateOpts{
+		Period:    o.period,
+		Skew:      skew,
+		Digits:    o.digits,
+		Algorithm: o.algorithm,
+	})
+	return err == nil && valid
+}
@@ -0,0 +1,161 @@
+// Package totp_test provides tests for the totp package.
+package totp_test
+
+import (
+	"bytes"
+	"image/png"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/catalogfi/tools/pkg/totp"
+	"github.com/pquerna/otp"
+	pqtotp "github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerateSecret verifies that generated keys carry the account details and default parameters.
+func TestGenerateSecret(t *testing.T) {
+	key, err := totp.GenerateSecret("moji", "alice@example.com")
+	require.NoError(t, err)
+
+	require.Equal(t, "totp", key.Type())
+	require.Equal(t, "moji", key.Issuer())
+	require.Equal(t, "alice@example.com", key.AccountName())
+	require.Equal(t, uint64(30), key.Period())
+	require.Equal(t, otp.DigitsSix, key.Digits())
+	require.Equal(t, otp.AlgorithmSHA1, key.Algorithm())
+	require.NotEmpty(t, key.Secret())
+
+	other, err := totp.GenerateSecret("moji", "alice@example.com")
+	require.NoError(t, err)
+	require.NotEqual(t, key.Secret(), other.Secret(), "secrets should be random")
+}
+
+// TestGenerateSecretRequiresAccount verifies that an account name is required.
+func TestGenerateSecretRequiresAccount(t *testing.T) {
+	_, err := totp.GenerateSecret("moji", "")
+	require.Error(t, err)
+}
+
+// TestRenderPNG verifies that the QR code is rendered as a PNG of the requested size.
+func TestRenderPNG(t *testing.T) {
+	key, err := totp.GenerateSecret("moji", "alice@example.com")
+	require.NoError(t, err)
+
+	data, err := totp.RenderPNG(key, 200)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, 200, img.Bounds().Dx())
+	require.Equal(t, 200, img.Bounds().Dy())
+}
+
+// TestValidate verifies that the current code is accepted and a wrong one rejected.
+func TestValidate(t *testing.T) {
+	key, err := totp.GenerateSecret("moji", "alice@example.com")
+	require.NoError(t, err)
+
+	code, err := pqtotp.GenerateCode(key.Secret(), time.Now())
+	require.NoError(t, err)
+	require.True(t, totp.Validate(key.Secret(), code))
+	require.False(t, totp.Validate(key.Secret(), "not-a-code"))
+	require.False(t, totp.Validate("invalid secret", code))
+}
+
+// TestValidateWithWindow verifies that codes from adjacent steps are only accepted within the window.
+func TestValidateWithWindow(t *testing.T) {
+	key, err := totp.GenerateSecret("moji", "alice@example.com")
+	require.NoError(t, err)
+
+	previous, err := pqtotp.GenerateCode(key.Secret(), time.Now().Add(-30*time.Second))
+	require.NoError(t, err)
+	require.True(t, totp.ValidateWithWindow(key.Secret(), previous, 1))
+
+	stale, err := pqtotp.GenerateCode(key.Secret(), time.Now().Add(-5*time.Minute))
+	require.NoError(t, err)
+	require.False(t, totp.ValidateWithWindow(key.Secret(), stale, 1))
+	require.True(t, totp.ValidateWithWindow(key.Secret(), stale, 11))
+}
+
+// TestGenerateSecretWithOptions verifies that the options are reflected in the key and its URL.
+func TestGenerateSecretWithOptions(t *testing.T) {
+	key, err := totp.GenerateSecret("moji", "alice@example.com",
+		totp.WithIssuer("catalog"),
+		totp.WithPeriod(60),
+		totp.WithDigits(otp.DigitsEight),
+		totp.WithAlgorithm(otp.AlgorithmSHA256),
+	)
+	require.NoError(t, err)
+
+	require.Equal(t, "catalog", key.Issuer())
+	require.Equal(t, uint64(60), key.Period())
+	require.Equal(t, otp.DigitsEight, key.Digits())
+	require.Equal(t, otp.AlgorithmSHA256, key.Algorithm())
+
+	parsed, err := url.Parse(key.URL())
+	require.NoError(t, err)
+	query := parsed.Query()
+	require.Equal(t, "catalog", query.Get("issuer"))
+	require.Equal(t, "60", query.Get("period"))
+	require.Equal(t, "8", query.Get("digits"))
+	require.Equal(t, "SHA256", query.Get("algorithm"))
+
+	code, err := pqtotp.GenerateCodeCustom(key.Secret(), time.Now(), pqtotp.ValidateOpts{
+		Period:    60,
+		Digits:    otp.DigitsEight,
+		Algorithm: otp.AlgorithmSHA256,
+	})
+	require.NoError(t, err)
+	require.Len(t, code, 8)
+	require.True(t, totp.Validate(key.Secret(), code, totp.WithPeriod(60), totp.WithDigits(otp.DigitsEight), totp.WithAlgorithm(otp.AlgorithmSHA256)))
+	require.False(t, totp.Validate(key.Secret(), code))
+}
+
+// TestKeyFromSecret verifies that a key rebuilt from a stored secret produces the same codes.
+func TestKeyFromSecret(t *testing.T) {
+	original, err := totp.GenerateSecret("moji", "alice@example.com", totp.WithDigits(otp.DigitsEight))
+	require.NoError(t, err)
+
+	key, err := totp.KeyFromSecret("moji", "alice@example.com", strings.ToLower(original.Secret()), totp.WithDigits(otp.DigitsEight))
+	require.NoError(t, err)
+	require.Equal(t, original.URL(), key.URL())
+
+	opts := pqtotp.ValidateOpts{Period: 30, Digits: otp.DigitsEight, Algorithm: otp.AlgorithmSHA1}
+	for _, at := range []time.Time{time.Now(), time.Now().Add(time.Hour)} {
+		want, err := pqtotp.GenerateCodeCustom(original.Secret(), at, opts)
+		require.NoError(t, err)
+		got, err := pqtotp.GenerateCodeCustom(key.Secret(), at, opts)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+
+	_, err = totp.RenderPNG(key, 200)
+	require.NoError(t, err)
+}
+
+// TestKeyFromSecretInvalid verifies that secrets which aren't base32 are rejected.
+func TestKeyFromSecretInvalid(t *testing.T) {
+	_, err := totp.KeyFromSecret("moji", "alice@example.com", "not base32!")
+	require.ErrorContains(t, err, "base32")
+}
+
+// TestValidateSecret verifies that well formed secrets are accepted regardless of case and padding, and that bad
+// characters and lengths are reported with distinct errors.
+func TestValidateSecret(t *testing.T) {
+	key, err := totp.GenerateSecret("moji", "alice@example.com")
+	require.NoError(t, err)
+	require.NoError(t, totp.ValidateSecret(key.Secret()))
+
+	require.NoError(t, totp.ValidateSecret("hxdmvjecjjwsrb3hwizr4ifugftmxboz"))
+	require.NoError(t, totp.ValidateSecret(" JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP= "))
+
+	require.ErrorIs(t, totp.ValidateSecret("HXDMVJECJJWSRB3HWIZR4IFUGFTMXB0Z"), totp.ErrInvalidSecretCharacter)
+	require.ErrorIs(t, totp.ValidateSecret("HXDMVJECJJWS-RB3HWIZR4IFUGFTMXBOZ"), totp.ErrInvalidSecretCharacter)
+
+	require.ErrorIs(t, totp.ValidateSecret(""), totp.ErrInvalidSecretLength)
+	require.ErrorIs(t, totp.ValidateSecret("JBSWY3DP"), totp.ErrInvalidSecretLength)
+	require.ErrorIs(t, totp.ValidateSecret("HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZA"), totp.ErrInvalidSecretLength)
+}
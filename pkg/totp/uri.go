@@ -0,0 +1,122 @@
+package totp
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	pqtotp "github.com/pquerna/otp/totp"
+)
+
+// ErrUnsupportedKeyType is returned for otpauth:// URIs and keys that aren't time-based
+var ErrUnsupportedKeyType = errors.New("unsupported otp type")
+
+// ParseURI parses an otpauth:// provisioning URI, such as the ones encoded in authenticator QR codes. Both totp
+// and hotp URIs are accepted, and the secret must be present and valid base32.
+func ParseURI(uri string) (*otp.Key, error) {
+	parsed, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		return nil, fmt.Errorf("invalid otpauth uri: %w", err)
+	}
+	if parsed.Scheme != "otpauth" {
+		return nil, fmt.Errorf("invalid otpauth uri: unexpected scheme %q", parsed.Scheme)
+	}
+	if parsed.Host != "totp" && parsed.Host != "hotp" {
+		return nil, fmt.Errorf("%w %q in otpauth uri", ErrUnsupportedKeyType, parsed.Host)
+	}
+	secret := parsed.Query().Get("secret")
+	if secret == "" {
+		return nil, errors.New("invalid otpauth uri: missing secret")
+	}
+	if _, err := decodeSecret(secret); err != nil {
+		return nil, fmt.Errorf("invalid otpauth uri: %w", err)
+	}
+
+	return otp.NewKeyFromURL(uri)
+}
+
+// ProvisioningURI builds the otpauth:// URI of a base32 secret without building a key, for example to hand it to a
+// frontend QR code library. The issuer and account name are escaped, including any colon which would otherwise
+// split the label, and WithIssuer overrides issuer like in GenerateSecret.
+func ProvisioningURI(issuer, accountName, secret string, opts ...Option) string {
+	o := applyOptions(append([]Option{WithIssuer(issuer)}, opts...))
+
+	label := escapeLabel(accountName)
+	params := url.Values{}
+	params.Set("secret", strings.TrimRight(strings.ToUpper(strings.TrimSpace(secret)), "="))
+	if o.issuer != "" {
+		label = escapeLabel(o.issuer) + ":" + label
+		params.Set("issuer", o.issuer)
+	}
+	params.Set("period", strconv.FormatUint(uint64(o.period), 10))
+	params.Set("digits", o.digits.String())
+	params.Set("algorithm", o.algorithm.String())
+
+	// Authenticator apps expect spaces as %20 rather than +, an actual + is already escaped as %2B
+	query := strings.ReplaceAll(params.Encode(), "+", "%20")
+	return "otpauth://totp/" + label + "?" + query
+}
+
+// escapeLabel escapes a part of the label of an otpauth:// URI, where a colon separates the issuer from the account
+func escapeLabel(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), ":", "%3A")
+}
+
+// CurrentCode returns the code of a time-based key for the current time step, using the key's own parameters
+func CurrentCode(key *otp.Key) (string, error) {
+	return codeAt(key, time.Now())
+}
+
+// CurrentCodes returns the codes of a time-based key from skew time steps before the current one to skew steps
+// after it, oldest first, so the current code is in the middle of the 2*skew+1 codes
+func CurrentCodes(key *otp.Key, skew uint) ([]string, error) {
+	now := time.Now()
+	period := time.Duration(key.Period()) * time.Second
+	codes := make([]string, 0, 2*skew+1)
+	for i := -int(skew); i <= int(skew); i++ {
+		code, err := codeAt(key, now.Add(time.Duration(i)*period))
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// GenerateCode returns the code of the base32 secret at the given time. The options must match the ones the secret
+// was generated with.
+func GenerateCode(secret string, at time.Time, opts ...Option) (string, error) {
+	if _, err := decodeSecret(secret); err != nil {
+		return "", err
+	}
+	o := applyOptions(opts)
+	code, err := pqtotp.GenerateCodeCustom(secret, at, pqtotp.ValidateOpts{
+		Period:    o.period,
+		Digits:    o.digits,
+		Algorithm: o.algorithm,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	return code, nil
+}
+
+// codeAt returns the code of a time-based key at the given time
+func codeAt(key *otp.Key, at time.Time) (string, error) {
+	if key.Type() != "totp" {
+		return "", fmt.Errorf("%w %q, expected totp", ErrUnsupportedKeyType, key.Type())
+	}
+	code, err := pqtotp.GenerateCodeCustom(key.Secret(), at, pqtotp.ValidateOpts{
+		Period:    uint(key.Period()),
+		Digits:    key.Digits(),
+		Algorithm: key.Algorithm(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	return code, nil
+}
@@ -0,0 +1,122 @@
+package totp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/catalogfi/tools/pkg/totp"
+	"github.com/pquerna/otp"
+	pqtotp "github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseURI verifies that the parameters of a known good URI are extracted.
+func TestParseURI(t *testing.T) {
+	key, err := totp.ParseURI("otpauth://totp/ACME%20Co:john@example.com?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ&issuer=ACME%20Co&algorithm=SHA256&digits=8&period=60")
+	require.NoError(t, err)
+
+	require.Equal(t, "totp", key.Type())
+	require.Equal(t, "ACME Co", key.Issuer())
+	require.Equal(t, "john@example.com", key.AccountName())
+	require.Equal(t, "HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ", key.Secret())
+	require.Equal(t, otp.AlgorithmSHA256, key.Algorithm())
+	require.Equal(t, otp.DigitsEight, key.Digits())
+	require.Equal(t, uint64(60), key.Period())
+
+	code, err := totp.CurrentCode(key)
+	require.NoError(t, err)
+	valid, err := pqtotp.ValidateCustom(code, key.Secret(), time.Now(), pqtotp.ValidateOpts{
+		Period:    60,
+		Skew:      1,
+		Digits:    otp.DigitsEight,
+		Algorithm: otp.AlgorithmSHA256,
+	})
+	require.NoError(t, err)
+	require.True(t, valid)
+}
+
+// TestParseURIInvalid verifies that malformed and unsupported URIs are rejected.
+func TestParseURIInvalid(t *testing.T) {
+	for _, uri := range []string{
+		"garbage",
+		"https://example.com/?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ",
+		"otpauth://totp/ACME:john?issuer=ACME",
+		"otpauth://totp/ACME:john?secret=not-base32!",
+		"otpauth://motp/ACME:john?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ",
+	} {
+		_, err := totp.ParseURI(uri)
+		require.Error(t, err, uri)
+	}
+
+	_, err := totp.ParseURI("otpauth://motp/ACME:john?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ")
+	require.ErrorIs(t, err, totp.ErrUnsupportedKeyType)
+}
+
+// TestGenerateCode verifies codes generated from a bare secret and the rejection of invalid secrets.
+func TestGenerateCode(t *testing.T) {
+	at := time.Now()
+	code, err := totp.GenerateCode("HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ", at)
+	require.NoError(t, err)
+
+	want, err := pqtotp.GenerateCode("HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ", at)
+	require.NoError(t, err)
+	require.Equal(t, want, code)
+
+	_, err = totp.GenerateCode("not base32!", at)
+	require.Error(t, err)
+}
+
+// TestCurrentCodes verifies that the current code is surrounded by skew codes on each side.
+func TestCurrentCodes(t *testing.T) {
+	key, err := totp.GenerateSecret("Test", "test@example.com")
+	require.NoError(t, err)
+
+	// Retry if the time step changes while the codes are generated
+	for {
+		before, err := totp.CurrentCode(key)
+		require.NoError(t, err)
+		codes, err := totp.CurrentCodes(key, 2)
+		require.NoError(t, err)
+		previous, err := totp.GenerateCode(key.Secret(), time.Now().Add(-30*time.Second))
+		require.NoError(t, err)
+		after, err := totp.CurrentCode(key)
+		require.NoError(t, err)
+		if before != after {
+			continue
+		}
+
+		require.Len(t, codes, 5)
+		require.Equal(t, before, codes[2])
+		require.Equal(t, previous, codes[1])
+		break
+	}
+
+	codes, err := totp.CurrentCodes(key, 0)
+	require.NoError(t, err)
+	require.Len(t, codes, 1)
+
+	hotpKey, err := totp.GenerateHOTPSecret("Test", "test@example.com")
+	require.NoError(t, err)
+	_, err = totp.CurrentCodes(hotpKey, 1)
+	require.ErrorIs(t, err, totp.ErrUnsupportedKeyType)
+}
+
+// TestProvisioningURI verifies the URI against a known good one and that it round trips through ParseURI.
+func TestProvisioningURI(t *testing.T) {
+	uri := totp.ProvisioningURI("ACME Co", "john:doe@example.com", "hxdmvjecjjwsrb3hwizr4ifugftmxboz")
+	require.Equal(t, "otpauth://totp/ACME%20Co:john%3Adoe@example.com?algorithm=SHA1&digits=6&issuer=ACME%20Co&period=30&secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ", uri)
+
+	key, err := totp.ParseURI(uri)
+	require.NoError(t, err)
+	require.Equal(t, "ACME Co", key.Issuer())
+	require.Equal(t, "john:doe@example.com", key.AccountName())
+
+	uri = totp.ProvisioningURI("", "a+b@example.com", "HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ",
+		totp.WithDigits(otp.DigitsEight), totp.WithAlgorithm(otp.AlgorithmSHA256), totp.WithPeriod(60))
+	require.Equal(t, "otpauth://totp/a+b@example.com?algorithm=SHA256&digits=8&period=60&secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ", uri)
+
+	key, err = totp.ParseURI(uri)
+	require.NoError(t, err)
+	require.Equal(t, "a+b@example.com", key.AccountName())
+	require.Equal(t, uint64(60), key.Period())
+}
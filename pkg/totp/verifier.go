@@ -0,0 +1,61 @@
+package totp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/catalogfi/tools/pkg/memcache"
+)
+
+// ErrLockedOut is returned by Verifier.Validate when an account has too many recent failed attempts
+var ErrLockedOut = errors.New("too many failed attempts, account is locked out")
+
+// ErrInvalidLockout is returned when the threshold or window of a Verifier isn't positive
+var ErrInvalidLockout = errors.New("lockout threshold and window must be positive")
+
+// Verifier validates codes while throttling brute force attempts. It counts the failed attempts of each account in
+// memory, and once an account reaches the threshold, every validation is rejected with ErrLockedOut until the
+// window started by its first failure ends. A successful validation resets the count. Counts live in a
+// memcache.IntCache, so they aren't shared between processes and, like any cache entry, may be evicted early.
+type Verifier struct {
+	failures  *memcache.IntCache[string]
+	threshold int64
+	opts      []Option
+}
+
+// NewVerifier returns a verifier locking an account out after threshold failed attempts within window. The options
+// must match the ones the secrets were generated with. Close must be called once the verifier is no longer needed.
+func NewVerifier(threshold int, window time.Duration, opts ...Option) (*Verifier, error) {
+	if threshold <= 0 || window <= 0 {
+		return nil, ErrInvalidLockout
+	}
+	failures, err := memcache.NewIntCache[string](
+		memcache.WithTtl(window),
+		memcache.WithIgnoreInternalCost(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create failure cache: %w", err)
+	}
+	return &Verifier{failures: failures, threshold: int64(threshold), opts: opts}, nil
+}
+
+// Validate reports whether code is valid for the secret of account, like Validate. It returns ErrLockedOut without
+// checking the code while the account is locked out.
+func (v *Verifier) Validate(account, secret, code string) (bool, error) {
+	if failures, _ := v.failures.Get(account); failures >= v.threshold {
+		return false, ErrLockedOut
+	}
+
+	if Validate(secret, code, v.opts...) {
+		v.failures.Delete(account)
+		return true, nil
+	}
+	v.failures.Increment(account, 1)
+	return false, nil
+}
+
+// Close releases the failure counts
+func (v *Verifier) Close() {
+	v.failures.Close()
+}
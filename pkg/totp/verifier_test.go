@@ -0,0 +1,71 @@
+package totp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/catalogfi/tools/pkg/totp"
+	"github.com/stretchr/testify/require"
+)
+
+// TestVerifierLockout verifies that an account is locked out after too many failures until the window ends.
+func TestVerifierLockout(t *testing.T) {
+	verifier, err := totp.NewVerifier(3, 200*time.Millisecond)
+	require.NoError(t, err)
+	defer verifier.Close()
+
+	key, err := totp.GenerateSecret("Test", "test@example.com")
+	require.NoError(t, err)
+	code, err := totp.CurrentCode(key)
+	require.NoError(t, err)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	for i := 0; i < 3; i++ {
+		valid, err := verifier.Validate("alice", key.Secret(), wrong)
+		require.NoError(t, err)
+		require.False(t, valid)
+	}
+
+	// Even the right code is rejected while locked out, and other accounts aren't affected
+	_, err = verifier.Validate("alice", key.Secret(), code)
+	require.ErrorIs(t, err, totp.ErrLockedOut)
+	valid, err := verifier.Validate("bob", key.Secret(), code)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	require.Eventually(t, func() bool {
+		valid, err := verifier.Validate("alice", key.Secret(), code)
+		return err == nil && valid
+	}, 2*time.Second, 50*time.Millisecond)
+}
+
+// TestVerifierResetsOnSuccess verifies that a successful validation clears the failed attempts.
+func TestVerifierResetsOnSuccess(t *testing.T) {
+	verifier, err := totp.NewVerifier(2, time.Minute)
+	require.NoError(t, err)
+	defer verifier.Close()
+
+	key, err := totp.GenerateSecret("Test", "test@example.com")
+	require.NoError(t, err)
+	code, err := totp.CurrentCode(key)
+	require.NoError(t, err)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	for i := 0; i < 3; i++ {
+		valid, err := verifier.Validate("alice", key.Secret(), wrong)
+		require.NoError(t, err)
+		require.False(t, valid)
+		valid, err = verifier.Validate("alice", key.Secret(), code)
+		require.NoError(t, err)
+		require.True(t, valid)
+	}
+
+	_, err = totp.NewVerifier(0, time.Minute)
+	require.ErrorIs(t, err, totp.ErrInvalidLockout)
+}
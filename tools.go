@@ -8,6 +8,8 @@ import (
 
 var NewAES256 = cryptutil.NewAES256
 
+var GenerateKey = cryptutil.GenerateKey
+
 var LoadConfigFromFile = config.LoadFromFile
 
 var NewParser = config.NewParser
@@ -15,3 +17,58 @@ var NewParser = config.NewParser
 func NewMemCache[V any](options ...memcache.Options) (memcache.Cache[V], error) {
 	return memcache.New[V](options...)
 }
+
+// Encrypt encrypts data with the hex-encoded AES-256 key. Each call parses the key again, so reuse an instance from
+// NewAES256 when encrypting repeatedly with the same key.
+func Encrypt(hexKey string, data []byte) ([]byte, error) {
+	aes, err := cryptutil.NewAES256(hexKey)
+	if err != nil {
+		return nil, err
+	}
+	return aes.Encrypt(data)
+}
+
+// Decrypt decrypts data encrypted by Encrypt with the same key, see Encrypt.
+func Decrypt(hexKey string, data []byte) ([]byte, error) {
+	aes, err := cryptutil.NewAES256(hexKey)
+	if err != nil {
+		return nil, err
+	}
+	return aes.Decrypt(data)
+}
+
+// EncryptToHex is like Encrypt but returns the ciphertext as a hex string.
+func EncryptToHex(hexKey string, data []byte) (string, error) {
+	aes, err := cryptutil.NewAES256(hexKey)
+	if err != nil {
+		return "", err
+	}
+	return aes.EncryptToHex(data)
+}
+
+// DecryptHex is like Decrypt but takes the ciphertext as a hex string.
+func DecryptHex(hexKey, hexData string) ([]byte, error) {
+	aes, err := cryptutil.NewAES256(hexKey)
+	if err != nil {
+		return nil, err
+	}
+	return aes.DecryptHex(hexData)
+}
+
+// EncryptStringToHex is like EncryptToHex for a string.
+func EncryptStringToHex(hexKey, plaintext string) (string, error) {
+	aes, err := cryptutil.NewAES256(hexKey)
+	if err != nil {
+		return "", err
+	}
+	return aes.EncryptStringToHex(plaintext)
+}
+
+// DecryptHexToString is like DecryptHex but returns the plaintext as a string.
+func DecryptHexToString(hexKey, hexData string) (string, error) {
+	aes, err := cryptutil.NewAES256(hexKey)
+	if err != nil {
+		return "", err
+	}
+	return aes.DecryptHexToString(hexData)
+}
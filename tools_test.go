@@ -0,0 +1,80 @@
+package tools_test
+
+import (
+	"testing"
+
+	"github.com/catalogfi/tools"
+	"github.com/catalogfi/tools/pkg/cryptutil"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+// TestEncryptDecrypt verifies that the one-shot helpers round trip and interoperate with an AES256 instance.
+func TestEncryptDecrypt(t *testing.T) {
+	encrypted, err := tools.Encrypt(testKey, []byte("secret"))
+	require.NoError(t, err)
+	decrypted, err := tools.Decrypt(testKey, encrypted)
+	require.NoError(t, err)
+	require.Equal(t, []byte("secret"), decrypted)
+
+	encryptedHex, err := tools.EncryptToHex(testKey, []byte("secret"))
+	require.NoError(t, err)
+	decrypted, err = tools.DecryptHex(testKey, encryptedHex)
+	require.NoError(t, err)
+	require.Equal(t, []byte("secret"), decrypted)
+
+	encryptedHex, err = tools.EncryptStringToHex(testKey, "secret")
+	require.NoError(t, err)
+	aes, err := tools.NewAES256(testKey)
+	require.NoError(t, err)
+	plaintext, err := aes.DecryptHexToString(encryptedHex)
+	require.NoError(t, err)
+	require.Equal(t, "secret", plaintext)
+
+	encryptedHex, err = aes.EncryptStringToHex("secret")
+	require.NoError(t, err)
+	plaintext, err = tools.DecryptHexToString(testKey, encryptedHex)
+	require.NoError(t, err)
+	require.Equal(t, "secret", plaintext)
+}
+
+// TestEncryptDecryptErrors verifies that key and data errors are passed through.
+func TestEncryptDecryptErrors(t *testing.T) {
+	_, err := tools.Encrypt("", []byte("secret"))
+	require.ErrorIs(t, err, cryptutil.ErrEmptyKey)
+	_, err = tools.Encrypt("abcd", []byte("secret"))
+	require.ErrorIs(t, err, cryptutil.ErrInvalidKeyLength)
+	_, err = tools.EncryptStringToHex(testKey, "")
+	require.ErrorIs(t, err, cryptutil.ErrEmptyData)
+
+	encrypted, err := tools.Encrypt(testKey, []byte("secret"))
+	require.NoError(t, err)
+	_, err = tools.Decrypt("ffeeddccbbaa99887766554433221100ffeeddccbbaa99887766554433221100", encrypted)
+	require.Error(t, err)
+	_, err = tools.DecryptHexToString(testKey, "not hex")
+	require.Error(t, err)
+}
+
+// TestAliases is a smoke test of the aliases, using only the tools package.
+func TestAliases(t *testing.T) {
+	key, err := tools.GenerateKey()
+	require.NoError(t, err)
+	aes, err := tools.NewAES256(key)
+	require.NoError(t, err)
+	encrypted, err := aes.EncryptStringToHex("secret")
+	require.NoError(t, err)
+	plaintext, err := tools.DecryptHexToString(key, encrypted)
+	require.NoError(t, err)
+	require.Equal(t, "secret", plaintext)
+
+	cache, err := tools.NewMemCache[string]()
+	require.NoError(t, err)
+	defer cache.Close()
+	require.True(t, cache.Set("key", "value"))
+	value, found := cache.Get("key")
+	require.True(t, found)
+	require.Equal(t, "value", value)
+
+	require.NotNil(t, tools.NewParser(key))
+}